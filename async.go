@@ -0,0 +1,187 @@
+package log
+
+import (
+	stdcontext "context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kit/log/internal/drain"
+)
+
+// ErrClosed is returned by Log when it is called on a Logger that has been
+// closed.
+var ErrClosed = errors.New("logger closed")
+
+// OverflowPolicy determines what an AsyncLogger does with a log event when
+// its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Log wait until the queue has room for the log
+	// event. No log events are dropped.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the log event passed to Log when the queue
+	// is full. Log never blocks.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest queued log event to make room
+	// for the log event passed to Log. Log never blocks.
+	OverflowDropOldest
+)
+
+// AsyncLogger passes log events to a wrapped Logger from a background
+// goroutine. See NewAsyncLogger for details.
+type AsyncLogger struct {
+	// Accessed atomically, kept first for 64-bit alignment on 32-bit
+	// platforms.
+	dropped uint64
+	drain   drain.Tracker
+
+	next         Logger
+	size         int
+	policy       OverflowPolicy
+	errorHandler func(error)
+	queue        chan []interface{}
+	done         chan struct{}
+
+	// mu guards closed and prevents Close from closing queue while a call to
+	// Log is sending on it.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// AsyncOption sets a parameter for an AsyncLogger.
+type AsyncOption func(*AsyncLogger)
+
+// AsyncQueueSize sets the maximum number of log events that may wait in the
+// queue of an AsyncLogger. The default is 1024. Values less than one are
+// ignored.
+func AsyncQueueSize(n int) AsyncOption {
+	return func(l *AsyncLogger) {
+		if n > 0 {
+			l.size = n
+		}
+	}
+}
+
+// AsyncOverflow sets the policy an AsyncLogger applies to log events that
+// arrive while its queue is full. The default is OverflowBlock.
+func AsyncOverflow(p OverflowPolicy) AsyncOption {
+	return func(l *AsyncLogger) { l.policy = p }
+}
+
+// AsyncErrorHandler sets a function that receives the errors returned by the
+// wrapped Logger. Those errors cannot be returned from Log, which returns
+// before the log event is written. By default they are discarded.
+func AsyncErrorHandler(f func(error)) AsyncOption {
+	return func(l *AsyncLogger) { l.errorHandler = f }
+}
+
+// NewAsyncLogger returns a Logger that hands log events to next from a
+// background goroutine, so that callers never wait for a slow io.Writer.
+// Log events are held in a bounded queue and passed to next in the order
+// they were enqueued. What happens when the queue is full depends on the
+// OverflowPolicy; log events discarded under a drop policy are counted and
+// reported by Dropped.
+//
+// Only one goroutine calls next.Log, so next need not be safe for concurrent
+// use. Errors returned by next are not returned by Log; see
+// AsyncErrorHandler.
+//
+// Call Close before the program exits to write any queued log events.
+func NewAsyncLogger(next Logger, options ...AsyncOption) *AsyncLogger {
+	l := &AsyncLogger{
+		next: next,
+		size: 1024,
+		done: make(chan struct{}),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	l.queue = make(chan []interface{}, l.size)
+	go l.run()
+	return l
+}
+
+// Log copies keyvals to the queue and returns without waiting for them to be
+// written. It returns ErrClosed if the AsyncLogger has been closed.
+func (l *AsyncLogger) Log(keyvals ...interface{}) error {
+	kvs := make([]interface{}, len(keyvals))
+	copy(kvs, keyvals)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return ErrClosed
+	}
+	l.drain.Submit()
+
+	switch l.policy {
+	case OverflowDropNewest:
+		select {
+		case l.queue <- kvs:
+		default:
+			l.drop()
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case l.queue <- kvs:
+				return nil
+			default:
+			}
+			select {
+			case <-l.queue:
+				l.drop()
+			default:
+			}
+		}
+	default:
+		l.queue <- kvs
+	}
+	return nil
+}
+
+// Dropped returns the number of log events discarded because the queue was
+// full.
+func (l *AsyncLogger) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// Flush blocks until the log events passed to Log before the call to Flush
+// have been written to the wrapped Logger or dropped, or until ctx is done.
+// It returns ctx.Err() if ctx is done first.
+func (l *AsyncLogger) Flush(ctx stdcontext.Context) error {
+	return l.drain.Wait(ctx, l.drain.Submitted())
+}
+
+// Close stops accepting log events and blocks until all queued log events
+// have been written to the wrapped Logger. Calls to Log after Close return
+// ErrClosed. Close is safe to call more than once.
+func (l *AsyncLogger) Close() error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+	<-l.done
+	return nil
+}
+
+func (l *AsyncLogger) run() {
+	defer close(l.done)
+	for kvs := range l.queue {
+		if err := l.next.Log(kvs...); err != nil && l.errorHandler != nil {
+			l.errorHandler(err)
+		}
+		l.drain.Complete(1)
+	}
+}
+
+func (l *AsyncLogger) drop() {
+	atomic.AddUint64(&l.dropped, 1)
+	l.drain.Complete(1)
+}
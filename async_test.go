@@ -0,0 +1,230 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestAsyncLogger(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewAsyncLogger(log.NewLogfmtLogger(buf))
+
+	for i := 0; i < 3; i++ {
+		if err := logger.Log("i", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "i=0\ni=1\ni=2\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+	if want, have := log.ErrClosed, logger.Log("i", 3); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if err := logger.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestAsyncLoggerCopiesKeyvals(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewAsyncLogger(log.NewLogfmtLogger(buf))
+
+	kvs := []interface{}{"k", "v"}
+	logger.Log(kvs...)
+	kvs[1] = "changed"
+	logger.Close()
+
+	if want, have := "k=v\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+}
+
+// gate is a Logger that records log events after release is closed.
+type gate struct {
+	entered chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	events  []interface{}
+}
+
+func newGate() *gate {
+	return &gate{
+		entered: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+}
+
+func (g *gate) Log(keyvals ...interface{}) error {
+	select {
+	case g.entered <- struct{}{}:
+	default:
+	}
+	<-g.release
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.events = append(g.events, keyvals[1])
+	return nil
+}
+
+func TestAsyncLoggerDropNewest(t *testing.T) {
+	t.Parallel()
+	g := newGate()
+	logger := log.NewAsyncLogger(g, log.AsyncQueueSize(2), log.AsyncOverflow(log.OverflowDropNewest))
+
+	// Wait for the first event to be held by the gate so that the queue is
+	// empty before filling it.
+	logger.Log("i", 0)
+	<-g.entered
+	for i := 1; i < 6; i++ {
+		if err := logger.Log("i", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(g.release)
+	logger.Close()
+
+	if want, have := []interface{}{0, 1, 2}, g.events; !equalEvents(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := uint64(3), logger.Dropped(); want != have {
+		t.Errorf("want %d dropped, have %d", want, have)
+	}
+}
+
+func TestAsyncLoggerDropOldest(t *testing.T) {
+	t.Parallel()
+	g := newGate()
+	logger := log.NewAsyncLogger(g, log.AsyncQueueSize(2), log.AsyncOverflow(log.OverflowDropOldest))
+
+	logger.Log("i", 0)
+	<-g.entered
+	for i := 1; i < 6; i++ {
+		if err := logger.Log("i", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(g.release)
+	logger.Close()
+
+	if want, have := []interface{}{0, 4, 5}, g.events; !equalEvents(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := uint64(3), logger.Dropped(); want != have {
+		t.Errorf("want %d dropped, have %d", want, have)
+	}
+}
+
+func TestAsyncLoggerBlock(t *testing.T) {
+	t.Parallel()
+	g := newGate()
+	logger := log.NewAsyncLogger(g, log.AsyncQueueSize(1))
+
+	logged := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			logger.Log("i", i)
+		}
+		close(logged)
+	}()
+
+	select {
+	case <-logged:
+		t.Fatal("Log did not block on a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(g.release)
+	<-logged
+	logger.Close()
+
+	if want, have := []interface{}{0, 1, 2, 3, 4}, g.events; !equalEvents(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := uint64(0), logger.Dropped(); want != have {
+		t.Errorf("want %d dropped, have %d", want, have)
+	}
+}
+
+func TestAsyncLoggerFlush(t *testing.T) {
+	t.Parallel()
+	g := newGate()
+	logger := log.NewAsyncLogger(g)
+	defer logger.Close()
+
+	logger.Log("i", 0)
+	logger.Log("i", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if want, have := context.DeadlineExceeded, logger.Flush(ctx); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	close(g.release)
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if want, have := []interface{}{0, 1}, g.events; !equalEvents(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestAsyncLoggerErrorHandler(t *testing.T) {
+	t.Parallel()
+	errLog := errors.New("log failed")
+	var errs []error
+	logger := log.NewAsyncLogger(
+		log.LoggerFunc(func(...interface{}) error { return errLog }),
+		log.AsyncErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	if err := logger.Log("k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Close()
+
+	if want, have := 1, len(errs); want != have {
+		t.Fatalf("want %d errors, have %d", want, have)
+	}
+	if want, have := errLog, errs[0]; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestAsyncLoggerConcurrency(t *testing.T) {
+	t.Parallel()
+	logger := log.NewAsyncLogger(log.NewLogfmtLogger(ioutil.Discard), log.AsyncOverflow(log.OverflowDropOldest))
+	testConcurrency(t, logger, 10000)
+	logger.Close()
+}
+
+func BenchmarkAsyncLoggerSimple(b *testing.B) {
+	logger := log.NewAsyncLogger(log.NewLogfmtLogger(ioutil.Discard))
+	defer logger.Close()
+	benchmarkRunner(b, logger, baseMessage)
+}
+
+func equalEvents(want, have []interface{}) bool {
+	if len(want) != len(have) {
+		return false
+	}
+	for i := range want {
+		if want[i] != have[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,9 @@
 package log_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 )
@@ -16,6 +18,26 @@ func benchmarkRunner(b *testing.B, logger log.Logger, f func(log.Logger)) {
 }
 
 var (
-	baseMessage = func(logger log.Logger) { logger.Log("foo_key", "foo_value") }
-	withMessage = func(logger log.Logger) { log.With(logger, "a", "b").Log("c", "d") }
+	baseMessage  = func(logger log.Logger) { logger.Log("foo_key", "foo_value") }
+	withMessage  = func(logger log.Logger) { log.With(logger, "a", "b").Log("c", "d") }
+	typedMessage = func(logger log.Logger) {
+		logger.Log(
+			"ts", benchTime,
+			"msg", "request handled",
+			"status", 200,
+			"dur", 0.125,
+			"cached", true,
+			"err", benchErr,
+			"method", benchStringer("GET"),
+		)
+	}
+)
+
+var (
+	benchTime = time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	benchErr  = errors.New("not found")
 )
+
+type benchStringer string
+
+func (s benchStringer) String() string { return string(s) }
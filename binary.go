@@ -0,0 +1,269 @@
+package log
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxBinaryDepth limits how deeply the binary encoders and decoders descend
+// into nested values, which guards against cyclic data structures.
+const maxBinaryDepth = 32
+
+// maxBinaryPrealloc caps the capacity a decoder allocates up front for a
+// string, array or map, so that a corrupt length cannot force a huge
+// allocation.
+const maxBinaryPrealloc = 1024
+
+// maxPooledBinaryBuffer limits the size of the buffers kept in binaryBufPool
+// so that one very large log event does not pin its memory indefinitely.
+const maxPooledBinaryBuffer = 64 << 10
+
+type binaryBuf struct {
+	b []byte
+}
+
+var binaryBufPool = sync.Pool{
+	New: func() interface{} { return &binaryBuf{} },
+}
+
+// binaryFormat appends data items in a binary serialization format. The
+// binary loggers share the mapping of Go values onto those items, so each
+// format only provides the encoding of the basic types.
+type binaryFormat interface {
+	appendNil(b []byte) []byte
+	appendBool(b []byte, v bool) []byte
+	appendInt(b []byte, v int64) []byte
+	appendUint(b []byte, v uint64) []byte
+	appendFloat32(b []byte, v float32) []byte
+	appendFloat64(b []byte, v float64) []byte
+	appendString(b []byte, v string) []byte
+	appendBytes(b []byte, v []byte) []byte
+	appendTime(b []byte, v time.Time) []byte
+	appendArrayHeader(b []byte, n int) []byte
+	appendMapHeader(b []byte, n int) []byte
+}
+
+type binaryLogger struct {
+	w      io.Writer
+	format binaryFormat
+}
+
+func (l *binaryLogger) Log(keyvals ...interface{}) error {
+	buf := binaryBufPool.Get().(*binaryBuf)
+	defer func() {
+		if cap(buf.b) <= maxPooledBinaryBuffer {
+			binaryBufPool.Put(buf)
+		}
+	}()
+
+	f := l.format
+	b := f.appendMapHeader(buf.b[:0], (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		b = f.appendString(b, keyString(keyvals[i]))
+		var v interface{} = ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		b = appendBinaryValue(f, b, v, 0)
+	}
+	buf.b = b
+
+	// The Logger interface requires implementations to be safe for concurrent
+	// use by multiple goroutines. For this implementation that means making
+	// only one call to l.w.Write() for each call to Log.
+	_, err := l.w.Write(b)
+	return err
+}
+
+// appendBinaryValue appends v in format f. Errors and fmt.Stringers become
+// strings from their Error and String methods, encoding.TextMarshalers the
+// strings of their text, and timestamps from TimestampFormat Valuers times.
+// Slices, arrays, maps and structs become arrays and maps, and values with no
+// representation, such as channels, a placeholder like <unsupported chan>.
+func appendBinaryValue(f binaryFormat, b []byte, v interface{}, depth int) []byte {
+	switch x := v.(type) {
+	case nil:
+		return f.appendNil(b)
+	case bool:
+		return f.appendBool(b, x)
+	case int:
+		return f.appendInt(b, int64(x))
+	case int8:
+		return f.appendInt(b, int64(x))
+	case int16:
+		return f.appendInt(b, int64(x))
+	case int32:
+		return f.appendInt(b, int64(x))
+	case int64:
+		return f.appendInt(b, x)
+	case uint:
+		return f.appendUint(b, uint64(x))
+	case uint8:
+		return f.appendUint(b, uint64(x))
+	case uint16:
+		return f.appendUint(b, uint64(x))
+	case uint32:
+		return f.appendUint(b, uint64(x))
+	case uint64:
+		return f.appendUint(b, x)
+	case float32:
+		return f.appendFloat32(b, x)
+	case float64:
+		return f.appendFloat64(b, x)
+	case string:
+		return f.appendString(b, x)
+	case []byte:
+		return f.appendBytes(b, x)
+	case time.Time:
+		return f.appendTime(b, x)
+	case timeFormat:
+		return f.appendTime(b, x.time)
+	case encoding.TextMarshaler:
+		return appendBinaryValue(f, b, safeMarshalText(x), depth)
+	case error:
+		return appendBinaryValue(f, b, safeError(x), depth)
+	case fmt.Stringer:
+		return f.appendString(b, safeString(x))
+	}
+	return appendBinaryReflect(f, b, reflect.ValueOf(v), depth)
+}
+
+func appendBinaryReflect(f binaryFormat, b []byte, rv reflect.Value, depth int) []byte {
+	if depth >= maxBinaryDepth {
+		return f.appendString(b, "<max depth exceeded>")
+	}
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return f.appendNil(b)
+		}
+		return appendBinaryValue(f, b, rv.Elem().Interface(), depth+1)
+	case reflect.Bool:
+		return f.appendBool(b, rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.appendInt(b, rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return f.appendUint(b, rv.Uint())
+	case reflect.Float32:
+		return f.appendFloat32(b, float32(rv.Float()))
+	case reflect.Float64:
+		return f.appendFloat64(b, rv.Float())
+	case reflect.String:
+		return f.appendString(b, rv.String())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return f.appendNil(b)
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			p := make([]byte, rv.Len())
+			for i := range p {
+				p[i] = byte(rv.Index(i).Uint())
+			}
+			return f.appendBytes(b, p)
+		}
+		b = f.appendArrayHeader(b, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			b = appendBinaryValue(f, b, rv.Index(i).Interface(), depth+1)
+		}
+		return b
+	case reflect.Map:
+		if rv.IsNil() {
+			return f.appendNil(b)
+		}
+		keys := rv.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = keyString(k.Interface())
+		}
+		sort.Sort(mapKeys{names, keys})
+		b = f.appendMapHeader(b, len(keys))
+		for _, k := range keys {
+			b = appendBinaryValue(f, b, k.Interface(), depth+1)
+			b = appendBinaryValue(f, b, rv.MapIndex(k).Interface(), depth+1)
+		}
+		return b
+	case reflect.Struct:
+		fields := exportedFields(rv.Type())
+		b = f.appendMapHeader(b, len(fields))
+		for _, sf := range fields {
+			b = f.appendString(b, sf.name)
+			b = appendBinaryValue(f, b, rv.Field(sf.index).Interface(), depth+1)
+		}
+		return b
+	}
+	return f.appendString(b, unsupportedValue(rv.Interface()))
+}
+
+// safeMarshalText returns the text of m, or a description of the failure.
+func safeMarshalText(m encoding.TextMarshaler) (s interface{}) {
+	defer func() {
+		if panicVal := recover(); panicVal != nil {
+			if v := reflect.ValueOf(m); v.Kind() == reflect.Ptr && v.IsNil() {
+				s = nil
+			} else {
+				s = fmt.Sprintf("PANIC in MarshalText method: %v", panicVal)
+			}
+		}
+	}()
+	text, err := m.MarshalText()
+	if err != nil {
+		return fmt.Sprintf("ERROR in MarshalText method: %v", err)
+	}
+	return string(text)
+}
+
+// appendUint64 appends n in big-endian byte order.
+func appendUint64(b []byte, n uint64) []byte {
+	return append(b, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+		byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// decodedMap returns the decoded map with the given entries: a
+// map[string]interface{} if all the keys are strings, and a
+// map[interface{}]interface{} otherwise.
+func decodedMap(keys, values []interface{}) (interface{}, error) {
+	textKeys := true
+	for _, k := range keys {
+		if _, ok := k.(string); !ok {
+			textKeys = false
+			break
+		}
+	}
+	if textKeys {
+		m := make(map[string]interface{}, len(keys))
+		for i, k := range keys {
+			m[k.(string)] = values[i]
+		}
+		return m, nil
+	}
+	m := make(map[interface{}]interface{}, len(keys))
+	for i, k := range keys {
+		if k != nil && !reflect.TypeOf(k).Comparable() {
+			return nil, fmt.Errorf("unsupported map key type %T", k)
+		}
+		m[k] = values[i]
+	}
+	return m, nil
+}
+
+var errMaxDepth = errors.New("maximum nesting depth exceeded")
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,280 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// cborIndefinite is the additional information value that marks an
+// indefinite-length item, or the "break" stop code when used with major
+// type 7.
+const cborIndefinite = 31
+
+// errCBORBreak is returned by readItem when it reads the "break" stop code
+// that ends an indefinite-length item.
+var errCBORBreak = errors.New("cbor: unexpected break")
+
+// CBORDecoder reads log events written by a CBOR logger. It is intended for
+// tooling and tests rather than high-volume processing.
+type CBORDecoder struct {
+	r *bufio.Reader
+}
+
+// NewCBORDecoder returns a CBORDecoder that reads from r.
+func NewCBORDecoder(r io.Reader) *CBORDecoder {
+	return &CBORDecoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next log event and returns its key/value pairs in the
+// order they were encoded. It returns io.EOF when there are no more events,
+// and io.ErrUnexpectedEOF if the input ends part way through one.
+//
+// Text strings decode as string, byte strings as []byte, unsigned and
+// negative integers as uint64 and int64 respectively, floats as float64, and
+// epoch-based date/time (tag 1) as time.Time. Arrays decode as
+// []interface{}, and maps as map[string]interface{} when all their keys are
+// text strings or map[interface{}]interface{} otherwise. Other tags are
+// ignored and their content returned as is.
+func (d *CBORDecoder) Decode() ([]interface{}, error) {
+	if _, err := d.r.Peek(1); err != nil {
+		return nil, err
+	}
+	major, info, n, err := d.readHead()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	indefinite := info == cborIndefinite
+	if major != cborMap {
+		return nil, fmt.Errorf("cbor: log event is major type %d, not a map", major>>5)
+	}
+	var keyvals []interface{}
+	if !indefinite {
+		keyvals = make([]interface{}, 0, 2*min64(n, maxBinaryPrealloc))
+	}
+	for i := uint64(0); indefinite || i < n; i++ {
+		k, err := d.readItem(0)
+		if err == errCBORBreak && indefinite {
+			break
+		}
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		v, err := d.readItem(0)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		keyvals = append(keyvals, k, v)
+	}
+	return keyvals, nil
+}
+
+// readHead reads the initial byte of a data item, returning its major type
+// and additional information, and the argument that follows if any.
+func (d *CBORDecoder) readHead() (major, info byte, n uint64, err error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = c&0xe0, c&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		var buf [8]byte
+		size := 1 << (info - 24)
+		if _, err := io.ReadFull(d.r, buf[:size]); err != nil {
+			return 0, 0, 0, err
+		}
+		for _, b := range buf[:size] {
+			n = n<<8 | uint64(b)
+		}
+		return major, info, n, nil
+	case info == cborIndefinite:
+		switch major {
+		case cborBytes, cborText, cborArray, cborMap, cborSimple:
+			return major, info, 0, nil
+		}
+	}
+	return 0, 0, 0, fmt.Errorf("cbor: invalid initial byte %#x", c)
+}
+
+func (d *CBORDecoder) readItem(depth int) (interface{}, error) {
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("cbor: %w", errMaxDepth)
+	}
+	major, info, n, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := info == cborIndefinite
+	switch major {
+	case cborUint:
+		return n, nil
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: negative integer -1-%d overflows int64", n)
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		b, err := d.readString(major, n, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(b), nil
+		}
+		return b, nil
+	case cborArray:
+		var a []interface{}
+		if !indefinite {
+			a = make([]interface{}, 0, min64(n, maxBinaryPrealloc))
+		}
+		for i := uint64(0); indefinite || i < n; i++ {
+			v, err := d.readItem(depth + 1)
+			if err == errCBORBreak && indefinite {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	case cborMap:
+		return d.readMap(n, indefinite, depth)
+	case cborTag:
+		v, err := d.readItem(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if n == cborTagEpochTime {
+			return epochTime(v)
+		}
+		return v, nil
+	}
+	return readSimple(info, n)
+}
+
+// readString reads the content of a byte or text string, joining the chunks
+// of an indefinite-length string.
+func (d *CBORDecoder) readString(major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		b := make([]byte, 0, min64(n, maxBinaryPrealloc))
+		for n > 0 {
+			chunk := min64(n, maxBinaryPrealloc)
+			start := len(b)
+			b = append(b, make([]byte, chunk)...)
+			if _, err := io.ReadFull(d.r, b[start:]); err != nil {
+				return nil, err
+			}
+			n -= chunk
+		}
+		return b, nil
+	}
+	var b []byte
+	for {
+		chunkMajor, chunkInfo, chunkLen, err := d.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor == cborSimple && chunkInfo == cborIndefinite {
+			return b, nil
+		}
+		if chunkMajor != major || chunkInfo == cborIndefinite {
+			return nil, errors.New("cbor: invalid indefinite-length string chunk")
+		}
+		chunk, err := d.readString(major, chunkLen, false)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+func (d *CBORDecoder) readMap(n uint64, indefinite bool, depth int) (interface{}, error) {
+	var keys, values []interface{}
+	for i := uint64(0); indefinite || i < n; i++ {
+		k, err := d.readItem(depth + 1)
+		if err == errCBORBreak && indefinite {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.readItem(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		keys, values = append(keys, k), append(values, v)
+	}
+	m, err := decodedMap(keys, values)
+	if err != nil {
+		return nil, fmt.Errorf("cbor: %w", err)
+	}
+	return m, nil
+}
+
+// readSimple decodes a major type 7 item: a float, a simple value or the
+// break stop code.
+func readSimple(info byte, n uint64) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 25:
+		return float16to64(uint16(n)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 27:
+		return math.Float64frombits(n), nil
+	case cborIndefinite:
+		return nil, errCBORBreak
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+}
+
+// float16to64 converts an IEEE 754 half-precision float.
+func float16to64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(frac+1024, exp-25)
+}
+
+// epochTime converts the content of an epoch-based date/time tag.
+func epochTime(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case uint64:
+		if x > math.MaxInt64 {
+			break
+		}
+		return time.Unix(int64(x), 0), nil
+	case int64:
+		return time.Unix(x, 0), nil
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			break
+		}
+		sec, frac := math.Modf(x)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))), nil
+	}
+	return nil, fmt.Errorf("cbor: invalid epoch-based date/time %v", v)
+}
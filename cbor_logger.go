@@ -0,0 +1,124 @@
+package log
+
+import (
+	"io"
+	"math"
+	"time"
+)
+
+// CBOR major types, shifted into the high three bits of the initial byte.
+const (
+	cborUint   byte = 0 << 5
+	cborNegInt byte = 1 << 5
+	cborBytes  byte = 2 << 5
+	cborText   byte = 3 << 5
+	cborArray  byte = 4 << 5
+	cborMap    byte = 5 << 5
+	cborTag    byte = 6 << 5
+	cborSimple byte = 7 << 5
+)
+
+const (
+	cborFalse   = cborSimple | 20
+	cborTrue    = cborSimple | 21
+	cborNull    = cborSimple | 22
+	cborFloat32 = cborSimple | 26
+	cborFloat64 = cborSimple | 27
+
+	cborTagEpochTime = 1
+)
+
+// NewCBORLogger returns a Logger that encodes each log event to the Writer
+// as a CBOR map (RFC 8949). Each log event produces no more than one call to
+// w.Write, and the encoding is self-delimiting, so log events can be written
+// back to back to a stream and read with a CBORDecoder. The passed Writer
+// must be safe for concurrent use by multiple goroutines if the returned
+// Logger will be used concurrently.
+//
+// Keys are encoded as text strings. Integers, floats, booleans, nil, strings
+// and byte slices are encoded as the native CBOR types, and time.Time values,
+// including those from TimestampFormat Valuers, as epoch-based date/time
+// (tag 1); times with a fractional second are encoded as floats, which keep
+// about microsecond precision for present-day times. Errors and
+// fmt.Stringers are encoded as text from their Error and String methods, and
+// encoding.TextMarshalers as their text. Slices, arrays, maps and structs
+// become CBOR arrays and maps. Values that have no CBOR representation, such
+// as channels, are replaced with a placeholder like <unsupported chan>.
+func NewCBORLogger(w io.Writer) Logger {
+	return &binaryLogger{w: w, format: cborFormat{}}
+}
+
+// cborFormat implements binaryFormat for CBOR.
+type cborFormat struct{}
+
+func (cborFormat) appendNil(b []byte) []byte { return append(b, cborNull) }
+
+func (cborFormat) appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, cborTrue)
+	}
+	return append(b, cborFalse)
+}
+
+func (cborFormat) appendInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return appendCBORHead(b, cborNegInt, uint64(-1-v))
+	}
+	return appendCBORHead(b, cborUint, uint64(v))
+}
+
+func (cborFormat) appendUint(b []byte, v uint64) []byte {
+	return appendCBORHead(b, cborUint, v)
+}
+
+func (cborFormat) appendFloat32(b []byte, v float32) []byte {
+	n := math.Float32bits(v)
+	return append(b, cborFloat32, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (cborFormat) appendFloat64(b []byte, v float64) []byte {
+	return appendUint64(append(b, cborFloat64), math.Float64bits(v))
+}
+
+func (cborFormat) appendString(b []byte, v string) []byte {
+	b = appendCBORHead(b, cborText, uint64(len(v)))
+	return append(b, v...)
+}
+
+func (cborFormat) appendBytes(b []byte, v []byte) []byte {
+	b = appendCBORHead(b, cborBytes, uint64(len(v)))
+	return append(b, v...)
+}
+
+func (f cborFormat) appendTime(b []byte, t time.Time) []byte {
+	b = appendCBORHead(b, cborTag, cborTagEpochTime)
+	if t.Nanosecond() == 0 {
+		return f.appendInt(b, t.Unix())
+	}
+	return f.appendFloat64(b, float64(t.Unix())+float64(t.Nanosecond())/1e9)
+}
+
+func (cborFormat) appendArrayHeader(b []byte, n int) []byte {
+	return appendCBORHead(b, cborArray, uint64(n))
+}
+
+func (cborFormat) appendMapHeader(b []byte, n int) []byte {
+	return appendCBORHead(b, cborMap, uint64(n))
+}
+
+// appendCBORHead appends the initial byte of a data item of type major with
+// argument n, followed by n itself if it does not fit in the initial byte.
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(b, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(b, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		return appendUint64(append(b, major|27), n)
+	}
+}
@@ -0,0 +1,180 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestCBORLoggerEncoding(t *testing.T) {
+	t.Parallel()
+	// Expected encodings are from RFC 8949, Appendix A.
+	for _, test := range []struct {
+		value interface{}
+		want  string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{24, "1818"},
+		{uint16(1000), "1903e8"},
+		{int64(1000000000000), "1b000000e8d4a51000"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{-1, "20"},
+		{-1000, "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{float32(100000), "fa47c35000"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"IETF", "6449455446"},
+		{"\u00fc", "62c3bc"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{[]int{1, 2, 3}, "83010203"},
+		{map[string]interface{}{"a": 1, "b": []int{2, 3}}, "a26161016162820203"},
+		{time.Unix(1363896240, 0), "c11a514b67b0"},
+		{time.Unix(1363896240, 5e8), "c1fb41d452d9ec200000"},
+		{errors.New("IETF"), "6449455446"},
+		{(*int)(nil), "f6"},
+		{make(chan int), "723c756e737570706f72746564206368616e3e"},
+	} {
+		buf := &bytes.Buffer{}
+		if err := log.NewCBORLogger(buf).Log("k", test.value); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "a1616b"+test.want, hex.EncodeToString(buf.Bytes()); want != have {
+			t.Errorf("%#v:\nwant %s\nhave %s", test.value, want, have)
+		}
+	}
+}
+
+type cborStruct struct {
+	Name    string `json:"name"`
+	Skipped int    `json:"-"`
+	Count   int
+	private int
+}
+
+func TestCBORLoggerRoundTrip(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewCBORLogger(buf)
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := logger.Log(
+		"ts", log.TimestampFormat(func() time.Time { return ts }, time.RFC3339)(),
+		"msg", "hello",
+		"n", -42,
+		"err", errors.New("boom"),
+		"stringer", stringer("s"),
+		"nested", cborStruct{Name: "x", Skipped: 1, Count: 2, private: 3},
+		"ints", map[int]string{2: "b", 1: "a"},
+		"odd",
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Log("second", true); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := log.NewCBORDecoder(buf)
+	have, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		"ts", ts,
+		"msg", "hello",
+		"n", int64(-42),
+		"err", "boom",
+		"stringer", "s",
+		"nested", map[string]interface{}{"name": "x", "Count": uint64(2)},
+		"ints", map[interface{}]interface{}{uint64(1): "a", uint64(2): "b"},
+		"odd", log.ErrMissingValue.Error(),
+	}
+	if len(have) == len(want) {
+		// time.Time values must be compared with Equal.
+		if ht, ok := have[1].(time.Time); ok && ht.Equal(ts) {
+			have[1] = ts
+		}
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+
+	have, err = dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"second", true}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("want io.EOF, have %v", err)
+	}
+}
+
+func TestCBORDecoder(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		input string
+		want  []interface{}
+	}{
+		// Indefinite-length map, array, byte and text strings.
+		{"bf61610161629f0203ffff", []interface{}{"a", uint64(1), "b", []interface{}{uint64(2), uint64(3)}}},
+		{"a161615f42010243030405ff", []interface{}{"a", []byte{1, 2, 3, 4, 5}}},
+		{"a161617f657374726561646d696e67ff", []interface{}{"a", "streaming"}},
+		// Half-precision floats and unknown tags.
+		{"a2616bf93c00616cf97bff", []interface{}{"k", 1.0, "l", 65504.0}},
+		{"a1616bd82076687474703a2f2f7777772e6578616d706c652e636f6d", []interface{}{"k", "http://www.example.com"}},
+	} {
+		input, _ := hex.DecodeString(test.input)
+		have, err := log.NewCBORDecoder(bytes.NewReader(input)).Decode()
+		if err != nil {
+			t.Errorf("%s: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.want, have) {
+			t.Errorf("%s:\nwant %#v\nhave %#v", test.input, test.want, have)
+		}
+	}
+
+	for _, test := range []struct {
+		input string
+		want  error
+	}{
+		{"a2616b01", io.ErrUnexpectedEOF},
+		{"a1616b1a0102", io.ErrUnexpectedEOF},
+		{"", io.EOF},
+	} {
+		input, _ := hex.DecodeString(test.input)
+		if _, err := log.NewCBORDecoder(bytes.NewReader(input)).Decode(); err != test.want {
+			t.Errorf("%s: want %v, have %v", test.input, test.want, err)
+		}
+	}
+	if _, err := log.NewCBORDecoder(bytes.NewReader([]byte{0x01})).Decode(); err == nil {
+		t.Error("want error decoding a non-map log event")
+	}
+}
+
+func TestCBORLoggerConcurrency(t *testing.T) {
+	t.Parallel()
+	testConcurrency(t, log.NewCBORLogger(ioutil.Discard), 10000)
+}
+
+func BenchmarkCBORLoggerSimple(b *testing.B) {
+	benchmarkRunner(b, log.NewCBORLogger(ioutil.Discard), baseMessage)
+}
+
+func BenchmarkCBORLoggerContextual(b *testing.B) {
+	benchmarkRunner(b, log.NewCBORLogger(ioutil.Discard), withMessage)
+}
+
+func BenchmarkCBORLoggerTyped(b *testing.B) {
+	benchmarkRunner(b, log.NewCBORLogger(ioutil.Discard), typedMessage)
+}
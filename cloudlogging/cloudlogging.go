@@ -0,0 +1,195 @@
+// Package cloudlogging provides a Logger that writes log events as the
+// structured JSON that Google Cloud Logging agents and runtimes parse into
+// log entries.
+//
+//	logger := cloudlogging.NewLogger(log.NewSyncWriter(os.Stdout), cloudlogging.ProjectID("my-project"))
+//	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
+//	level.Warn(logger).Log("msg", "slow request", "trace_id", traceID)
+package cloudlogging
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/fields"
+	"github.com/go-kit/log/level"
+)
+
+// Special fields recognized by Cloud Logging.
+const (
+	sourceLocationField = "logging.googleapis.com/sourceLocation"
+	traceField          = "logging.googleapis.com/trace"
+	spanIDField         = "logging.googleapis.com/spanId"
+)
+
+// NewLogger returns a Logger that encodes each log event to w as a Cloud
+// Logging structured JSON object, with one call to w.Write. The passed Writer
+// must be safe for concurrent use by multiple goroutines if the returned
+// Logger will be used concurrently.
+//
+// The value under level.Key() becomes severity. The level names debug,
+// info, notice, warn, warning, error, critical, alert and emergency map to
+// the Cloud Logging severity of the same name, as do a few common
+// abbreviations such as crit; Severity adds more. Log events without a level,
+// or with one of an unknown name, have the DEFAULT severity. The "msg" value
+// becomes message, and a time.Time or timestamp Valuer value under "ts"
+// becomes time. A "file:line" value under "caller", as produced by
+// log.DefaultCaller, becomes logging.googleapis.com/sourceLocation; values
+// other than strings are formatted with fmt.Sprint first. The values under
+// the trace and span keys, which are set by TraceKey and SpanKey, become
+// logging.googleapis.com/trace and logging.googleapis.com/spanId. Other pairs
+// are written as they are, in the order they were logged, after those
+// fields.
+func NewLogger(w io.Writer, options ...Option) log.Logger {
+	l := &logger{
+		json:       log.NewJSONLoggerWithOptions(w, log.JSONPreserveKeyOrder()),
+		traceKey:   "trace_id",
+		spanKey:    "span_id",
+		severities: map[string]string{},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Option sets a parameter for the Logger returned by NewLogger.
+type Option func(*logger)
+
+// TraceKey sets the key whose value becomes the trace field. The default is
+// "trace_id".
+func TraceKey(key string) Option {
+	return func(l *logger) { l.traceKey = key }
+}
+
+// SpanKey sets the key whose value becomes the span ID field. The default is
+// "span_id".
+func SpanKey(key string) Option {
+	return func(l *logger) { l.spanKey = key }
+}
+
+// ProjectID sets the Google Cloud project that traces belong to. Cloud
+// Logging expects the trace field as a resource name of the form
+// projects/PROJECT_ID/traces/TRACE_ID; with a project ID set, trace IDs that
+// are not already in that form are written in it.
+func ProjectID(id string) Option {
+	return func(l *logger) { l.projectID = id }
+}
+
+// Severity maps the level name, compared without regard to case, to a Cloud
+// Logging severity such as "NOTICE". It extends or overrides the default
+// mapping described at NewLogger.
+func Severity(name, severity string) Option {
+	return func(l *logger) { l.severities[strings.ToLower(name)] = severity }
+}
+
+// defaultSeverities maps levels to Cloud Logging severities.
+var defaultSeverities = map[fields.Level]string{
+	fields.LevelTrace:     "DEBUG",
+	fields.LevelDebug:     "DEBUG",
+	fields.LevelInfo:      "INFO",
+	fields.LevelNotice:    "NOTICE",
+	fields.LevelWarn:      "WARNING",
+	fields.LevelError:     "ERROR",
+	fields.LevelCritical:  "CRITICAL",
+	fields.LevelAlert:     "ALERT",
+	fields.LevelFatal:     "CRITICAL",
+	fields.LevelEmergency: "EMERGENCY",
+}
+
+type logger struct {
+	json       log.Logger
+	traceKey   string
+	spanKey    string
+	projectID  string
+	severities map[string]string
+}
+
+// sourceLocation is the value of the sourceLocation field. The line is a
+// string because the LogEntrySourceLocation JSON representation encodes its
+// int64 line that way.
+type sourceLocation struct {
+	File string `json:"file"`
+	Line string `json:"line"`
+}
+
+func (l *logger) Log(keyvals ...interface{}) error {
+	var (
+		severity = "DEFAULT"
+		special  = make([]interface{}, 0, 12)
+		rest     = make([]interface{}, 0, len(keyvals))
+	)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		switch {
+		case k == level.Key():
+			severity = l.severity(v)
+			continue
+		case k == "msg":
+			special = append(special, "message", v)
+			continue
+		case k == "ts":
+			if t, ok := fields.Time(v); ok {
+				special = append(special, "time", t.UTC().Format(time.RFC3339Nano))
+				continue
+			}
+		case k == "caller":
+			if file, line, ok := fields.SplitCaller(v); ok {
+				loc := sourceLocation{File: file, Line: strconv.Itoa(line)}
+				special = append(special, sourceLocationField, loc)
+				continue
+			}
+		case k == l.traceKey:
+			special = append(special, traceField, l.trace(v))
+			continue
+		case k == l.spanKey:
+			special = append(special, spanIDField, v)
+			continue
+		}
+		rest = append(rest, k, v)
+	}
+
+	kvs := make([]interface{}, 0, 2+len(special)+len(rest))
+	kvs = append(kvs, "severity", severity)
+	kvs = append(kvs, special...)
+	kvs = append(kvs, rest...)
+	return l.json.Log(kvs...)
+}
+
+// severity returns the Cloud Logging severity for the level value v.
+func (l *logger) severity(v interface{}) string {
+	name, ok := v.(string)
+	if !ok {
+		name = fmt.Sprint(v)
+	}
+	if severity, ok := l.severities[strings.ToLower(name)]; ok {
+		return severity
+	}
+	if lvl, ok := fields.ParseLevel(name); ok {
+		return defaultSeverities[lvl]
+	}
+	return "DEFAULT"
+}
+
+// trace returns the trace field for the trace ID v.
+func (l *logger) trace(v interface{}) interface{} {
+	if l.projectID == "" {
+		return v
+	}
+	id, ok := v.(string)
+	if !ok {
+		id = fmt.Sprint(v)
+	}
+	if strings.HasPrefix(id, "projects/") {
+		return id
+	}
+	return "projects/" + l.projectID + "/traces/" + id
+}
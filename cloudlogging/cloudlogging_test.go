@@ -0,0 +1,85 @@
+package cloudlogging_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/cloudlogging"
+	"github.com/go-kit/log/level"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	ts := time.Date(2024, 5, 6, 9, 8, 9, 123456789, time.FixedZone("CEST", 2*60*60))
+	logger := log.With(cloudlogging.NewLogger(buf, cloudlogging.ProjectID("proj")),
+		"ts", log.TimestampFormat(func() time.Time { return ts }, time.RFC3339),
+		"caller", log.DefaultCaller,
+	)
+	if err := level.Warn(logger).Log("msg", "slow", "trace_id", "abc", "span_id", "0123", "path", "/"); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"severity":"WARNING","time":"2024-05-06T07:08:09.123456789Z","logging.googleapis.com/sourceLocation":{"file":"cloudlogging_test.go","line":"21"},"message":"slow","logging.googleapis.com/trace":"projects/proj/traces/abc","logging.googleapis.com/spanId":"0123","path":"/"}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestLoggerSeverity(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		level interface{}
+		want  string
+	}{
+		{level.DebugValue(), "DEBUG"},
+		{level.InfoValue(), "INFO"},
+		{level.WarnValue(), "WARNING"},
+		{level.ErrorValue(), "ERROR"},
+		{"notice", "NOTICE"},
+		{"CRIT", "CRITICAL"},
+		{"alert", "ALERT"},
+		{"emergency", "EMERGENCY"},
+		{"audit", "NOTICE"},
+		{"unknown", "DEFAULT"},
+	} {
+		buf := &bytes.Buffer{}
+		logger := cloudlogging.NewLogger(buf, cloudlogging.Severity("Audit", "NOTICE"))
+		if err := logger.Log("level", test.level); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := `{"severity":"`+test.want+`"}`+"\n", buf.String(); want != have {
+			t.Errorf("%v: want %s, have %s", test.level, want, have)
+		}
+	}
+}
+
+func TestLoggerKeys(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := cloudlogging.NewLogger(buf, cloudlogging.TraceKey("trace"), cloudlogging.SpanKey("span"), cloudlogging.ProjectID("proj"))
+	if err := logger.Log("trace", "projects/other/traces/t", "span", "s", "trace_id", "x", "caller", "no line"); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"severity":"DEFAULT","logging.googleapis.com/trace":"projects/other/traces/t","logging.googleapis.com/spanId":"s","trace_id":"x","caller":"no line"}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+type callerStringer string
+
+func (c callerStringer) String() string { return string(c) }
+
+func TestLoggerCallerStringer(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	if err := cloudlogging.NewLogger(buf).Log("caller", callerStringer("main.go:42")); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"severity":"DEFAULT","logging.googleapis.com/sourceLocation":{"file":"main.go","line":"42"}}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
@@ -0,0 +1,153 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupLogger collapses runs of identical log events. See NewDedupLogger for
+// details.
+type DedupLogger struct {
+	next    Logger
+	ignore  []string
+	timeout time.Duration
+
+	mu       sync.Mutex
+	last     string
+	hasLast  bool
+	repeated []interface{} // the last suppressed log event
+	count    uint64
+	timer    *time.Timer
+	gen      uint64 // incremented each time a run is summarized
+}
+
+// DedupOption sets a parameter for a DedupLogger.
+type DedupOption func(*DedupLogger)
+
+// DedupIgnoreKeys sets the keys whose values are ignored when comparing log
+// events. The default is "ts" and "caller", whose values usually differ
+// between otherwise identical log events.
+func DedupIgnoreKeys(keys ...string) DedupOption {
+	return func(l *DedupLogger) { l.ignore = keys }
+}
+
+// DedupTimeout sets the longest time a run of identical log events is held
+// back before it is summarized. When it expires the summary is written and a
+// new run begins. The default of zero waits until the run ends.
+func DedupTimeout(d time.Duration) DedupOption {
+	return func(l *DedupLogger) { l.timeout = d }
+}
+
+// NewDedupLogger returns a Logger that suppresses log events identical to
+// the log event before them, in the manner of syslogd. Log events are
+// identical if they have the same keys in the same order with the same
+// values, ignoring the values of the keys set with DedupIgnoreKeys.
+//
+// The first log event of a run is passed to next unmodified. When the run
+// ends, or its timeout expires, the last suppressed log event is passed to
+// next with a "repeated" key holding the number of log events suppressed.
+//
+//	msg="connection refused" ts=2006-01-02T15:04:05Z
+//	msg="connection refused" ts=2006-01-02T15:04:09Z repeated=41
+//
+// Calls to next are serialized to keep each summary next to its run. Call
+// Flush before the program exits to write the summary of a pending run.
+func NewDedupLogger(next Logger, options ...DedupOption) *DedupLogger {
+	l := &DedupLogger{
+		next:   next,
+		ignore: []string{"ts", "caller"},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Log passes keyvals to the wrapped Logger unless they are identical to the
+// previous log event.
+func (l *DedupLogger) Log(keyvals ...interface{}) error {
+	sig := l.signature(keyvals)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.hasLast && sig == l.last {
+		// The Logger interface forbids retaining keyvals without a copy.
+		l.repeated = append(l.repeated[:0], keyvals...)
+		l.count++
+		if l.count == 1 && l.timeout > 0 {
+			gen := l.gen
+			l.timer = time.AfterFunc(l.timeout, func() { l.expire(gen) })
+		}
+		return nil
+	}
+
+	err := l.summarize()
+	l.last, l.hasLast = sig, true
+	if lerr := l.next.Log(keyvals...); lerr != nil {
+		err = lerr
+	}
+	return err
+}
+
+// Flush writes the summary of the current run, if any. Log events that
+// follow are compared to the last log event as before.
+func (l *DedupLogger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.summarize()
+}
+
+func (l *DedupLogger) expire(gen uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if gen != l.gen {
+		return // the run was summarized before the timer fired
+	}
+	l.summarize()
+}
+
+// summarize writes the summary of the current run, if any, and starts a new
+// run. The caller must hold l.mu.
+func (l *DedupLogger) summarize() error {
+	if l.count == 0 {
+		return nil
+	}
+	kvs := append(l.repeated, "repeated", l.count)
+	l.repeated = kvs[:0]
+	l.count = 0
+	l.gen++
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	return l.next.Log(kvs...)
+}
+
+// signature returns a string that is equal for log events considered
+// identical.
+func (l *DedupLogger) signature(keyvals []interface{}) string {
+	var b strings.Builder
+	for i := 0; i < len(keyvals); i += 2 {
+		if l.ignored(keyvals[i]) {
+			continue
+		}
+		b.WriteString(keyString(keyvals[i]))
+		b.WriteByte(0)
+		if i+1 < len(keyvals) {
+			b.WriteString(keyString(keyvals[i+1]))
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func (l *DedupLogger) ignored(key interface{}) bool {
+	for _, k := range l.ignore {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package log_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestDedupLogger(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewDedupLogger(log.NewLogfmtLogger(buf))
+
+	logger.Log("msg", "a")
+	for i := 0; i < 3; i++ {
+		if err := logger.Log("msg", "b"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger.Log("msg", "c")
+	logger.Log("msg", "c")
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"msg=a",
+		"msg=b",
+		"msg=b repeated=2",
+		"msg=c",
+		"msg=c repeated=1",
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+func TestDedupLoggerValuers(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	var logger log.Logger = log.NewDedupLogger(log.NewLogfmtLogger(buf))
+
+	var tick int
+	clock := log.Valuer(func() interface{} { tick++; return tick })
+	logger = log.With(logger, "ts", clock, "caller", log.DefaultCaller)
+
+	for i := 0; i < 3; i++ {
+		logger.Log("msg", "same")
+	}
+	logger.Log("msg", "different")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if want, have := 3, len(lines); want != have {
+		t.Fatalf("want %d lines, have %d:\n%s", want, have, buf.String())
+	}
+	if want, have := "ts=1 ", lines[0]; !strings.HasPrefix(have, want) {
+		t.Errorf("want prefix %q, have %q", want, have)
+	}
+	// The summary carries the values bound for the last suppressed event.
+	if want, have := "ts=3 ", lines[1]; !strings.HasPrefix(have, want) {
+		t.Errorf("want prefix %q, have %q", want, have)
+	}
+	if want, have := " msg=same repeated=2", lines[1]; !strings.HasSuffix(have, want) {
+		t.Errorf("want suffix %q, have %q", want, have)
+	}
+	if want, have := " msg=different", lines[2]; !strings.HasSuffix(have, want) {
+		t.Errorf("want suffix %q, have %q", want, have)
+	}
+}
+
+func TestDedupLoggerIgnoreKeys(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewDedupLogger(log.NewLogfmtLogger(buf), log.DedupIgnoreKeys("id"))
+
+	logger.Log("msg", "m", "id", 1)
+	logger.Log("msg", "m", "id", 2)
+	logger.Log("msg", "m", "ts", 3)
+	logger.Flush()
+
+	want := strings.Join([]string{
+		"msg=m id=1",
+		"msg=m id=2 repeated=1",
+		"msg=m ts=3",
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+func TestDedupLoggerTimeout(t *testing.T) {
+	t.Parallel()
+	var (
+		mu  sync.Mutex
+		buf bytes.Buffer
+	)
+	next := log.LoggerFunc(func(keyvals ...interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return log.NewLogfmtLogger(&buf).Log(keyvals...)
+	})
+	logger := log.NewDedupLogger(next, log.DedupTimeout(10*time.Millisecond))
+
+	logger.Log("msg", "m")
+	logger.Log("msg", "m")
+	logger.Log("msg", "m")
+	time.Sleep(50 * time.Millisecond)
+	// The run continues after the timeout, so this starts a new count.
+	logger.Log("msg", "m")
+	logger.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := strings.Join([]string{
+		"msg=m",
+		"msg=m repeated=2",
+		"msg=m repeated=1",
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+func TestDedupLoggerConcurrency(t *testing.T) {
+	t.Parallel()
+	testConcurrency(t, log.NewDedupLogger(log.NewLogfmtLogger(ioutil.Discard)), 10000)
+}
+
+func BenchmarkDedupLoggerSimple(b *testing.B) {
+	benchmarkRunner(b, log.NewDedupLogger(log.NewLogfmtLogger(ioutil.Discard)), baseMessage)
+}
@@ -91,6 +91,12 @@
 // both the formatting and output logic. Use a SyncLogger if the formatting
 // logger may perform multiple writes per log event.
 //
+// NewAsyncLogger wraps any Logger and passes log events to it from a single
+// background goroutine through a bounded queue. Using an AsyncLogger has the
+// benefit that callers do not wait for slow writers, but log events are
+// written after Log returns, may be dropped if the queue overflows, and are
+// lost if the program exits without calling Close.
+//
 // # Error Handling
 //
 // This package relies on the practice of wrapping or decorating loggers with
@@ -0,0 +1,104 @@
+// Package ecs provides a Logger that writes log events as Elastic Common
+// Schema (ECS) JSON documents, which Elasticsearch can ingest without a
+// transform stage.
+//
+//	logger := ecs.NewLogger(log.NewSyncWriter(os.Stdout))
+//	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
+//	level.Info(logger).Log("msg", "listening", "addr", ":8080")
+//
+// produces
+//
+//	{"@timestamp":"2024-05-06T07:08:09.123Z","log.level":"info","message":"listening","ecs.version":"1.6.0","log.origin.file.name":"main.go","log.origin.file.line":14,"addr":":8080"}
+package ecs
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/fields"
+	"github.com/go-kit/log/level"
+)
+
+// Version is the ECS version written to the ecs.version field.
+const Version = "1.6.0"
+
+// timestampLayout is ISO 8601 with millisecond precision, as ECS expects.
+const timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// NewLogger returns a Logger that encodes each log event to w as an ECS JSON
+// document, with one call to w.Write. The passed Writer must be safe for
+// concurrent use by multiple goroutines if the returned Logger will be used
+// concurrently.
+//
+// The conventional keys are renamed to their ECS fields: "ts" to @timestamp,
+// level.Key() to log.level, "msg" to message and "err" to error.message. A
+// "file:line" value under "caller", as produced by log.DefaultCaller, is
+// split into log.origin.file.name and log.origin.file.line. The @timestamp
+// field is in UTC, and is the time of the Log call if the log event has no
+// "ts" time. The @timestamp, log.level, message and ecs.version fields come
+// first, and the other fields follow in the order they were logged. Fields
+// that the log event lacks, other than @timestamp and ecs.version, are
+// omitted.
+func NewLogger(w io.Writer) log.Logger {
+	return &logger{json: log.NewJSONLoggerWithOptions(w, log.JSONPreserveKeyOrder())}
+}
+
+type logger struct {
+	json log.Logger
+}
+
+func (l *logger) Log(keyvals ...interface{}) error {
+	var (
+		timestamp        time.Time
+		lvl, msg         interface{}
+		hasLevel, hasMsg bool
+		rest             = make([]interface{}, 0, len(keyvals)+2)
+	)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		switch k {
+		case "ts":
+			if t, ok := fields.Time(v); ok {
+				timestamp = t
+				continue
+			}
+		case level.Key():
+			lvl, hasLevel = v, true
+			continue
+		case "msg":
+			msg, hasMsg = v, true
+			continue
+		case "err":
+			rest = append(rest, "error.message", v)
+			continue
+		case "caller":
+			if file, line, ok := fields.SplitCaller(v); ok {
+				rest = append(rest, "log.origin.file.name", file, "log.origin.file.line", line)
+				continue
+			}
+			rest = append(rest, "log.origin.file.name", v)
+			continue
+		}
+		rest = append(rest, k, v)
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	kvs := make([]interface{}, 0, len(rest)+8)
+	kvs = append(kvs, "@timestamp", timestamp.UTC().Format(timestampLayout))
+	if hasLevel {
+		kvs = append(kvs, "log.level", lvl)
+	}
+	if hasMsg {
+		kvs = append(kvs, "message", msg)
+	}
+	kvs = append(kvs, "ecs.version", Version)
+	kvs = append(kvs, rest...)
+	return l.json.Log(kvs...)
+}
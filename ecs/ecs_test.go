@@ -0,0 +1,61 @@
+package ecs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/ecs"
+	"github.com/go-kit/log/level"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	ts := time.Date(2024, 5, 6, 9, 8, 9, 123456789, time.FixedZone("CEST", 2*60*60))
+	logger := log.With(ecs.NewLogger(buf),
+		"ts", log.TimestampFormat(func() time.Time { return ts }, time.RFC3339),
+		"caller", log.DefaultCaller,
+	)
+	if err := level.Error(logger).Log("msg", "request failed", "err", errors.New("timeout"), "path", "/"); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"@timestamp":"2024-05-06T07:08:09.123Z","log.level":"error","message":"request failed","ecs.version":"1.6.0","log.origin.file.name":"ecs_test.go","log.origin.file.line":23,"error.message":"timeout","path":"/"}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestLoggerMinimal(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	before := time.Now().Add(-time.Second)
+	if err := ecs.NewLogger(buf).Log("caller", "unknown", "odd"); err != nil {
+		t.Fatal(err)
+	}
+	var have map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := time.Parse(time.RFC3339, have["@timestamp"].(string))
+	if err != nil || ts.Before(before) {
+		t.Errorf("@timestamp: have %v, %v", have["@timestamp"], err)
+	}
+	for k, want := range map[string]interface{}{
+		"ecs.version":          ecs.Version,
+		"log.origin.file.name": "unknown",
+		"odd":                  log.ErrMissingValue.Error(),
+	} {
+		if have[k] != want {
+			t.Errorf("%s: want %v, have %v", k, want, have[k])
+		}
+	}
+	for _, k := range []string{"log.level", "message", "log.origin.file.line"} {
+		if _, ok := have[k]; ok {
+			t.Errorf("unexpected field %s", k)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package log
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultFlattenDepth is the maximum depth used when a non-positive depth is
+// configured. It guards against cyclic data structures.
+const defaultFlattenDepth = 10
+
+// flattener expands maps, structs, slices and arrays in keyvals into one
+// key/value pair per leaf value, joining the keys along the path with a
+// separator. The logfmt and JSON loggers share it so that both produce the
+// same fields.
+//
+// Values that implement error, fmt.Stringer, encoding.TextMarshaler or
+// json.Marshaler are leaves, as are []byte and time.Time. Map entries are
+// ordered by key, struct fields are named by their json tag if they have one,
+// and unexported or "-" tagged fields are skipped. Empty maps, slices and
+// arrays produce no pairs. Values nested deeper than maxDepth are left as
+// they are.
+type flattener struct {
+	maxDepth  int
+	separator string
+}
+
+func newFlattener(maxDepth int, separator string) *flattener {
+	if maxDepth < 1 {
+		maxDepth = defaultFlattenDepth
+	}
+	return &flattener{maxDepth: maxDepth, separator: separator}
+}
+
+// keyvals returns keyvals with every nested value expanded. Keys of pairs
+// that are not expanded are left untouched.
+func (f *flattener) keyvals(keyvals []interface{}) []interface{} {
+	kvs := make([]interface{}, 0, len(keyvals))
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			kvs = append(kvs, keyvals[i])
+			break
+		}
+		k, v := keyvals[i], keyvals[i+1]
+		rv, ok := nested(v)
+		if !ok {
+			kvs = append(kvs, k, v)
+			continue
+		}
+		kvs = f.appendValue(kvs, keyString(k), rv, 1)
+	}
+	return kvs
+}
+
+func (f *flattener) appendValue(kvs []interface{}, key string, rv reflect.Value, depth int) []interface{} {
+	if depth > f.maxDepth {
+		return append(kvs, key, rv.Interface())
+	}
+	child := func(name string, v reflect.Value) {
+		k := key + f.separator + name
+		if !v.CanInterface() {
+			return
+		}
+		if cv, ok := nested(v.Interface()); ok {
+			kvs = f.appendValue(kvs, k, cv, depth+1)
+			return
+		}
+		kvs = append(kvs, k, v.Interface())
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := rv.MapKeys()
+		names := make([]string, len(keys))
+		for i, mk := range keys {
+			names[i] = keyString(mk.Interface())
+		}
+		sort.Sort(mapKeys{names, keys})
+		for i, mk := range keys {
+			child(names[i], rv.MapIndex(mk))
+		}
+	case reflect.Struct:
+		for _, sf := range exportedFields(rv.Type()) {
+			child(sf.name, rv.Field(sf.index))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			child(strconv.Itoa(i), rv.Index(i))
+		}
+	}
+	return kvs
+}
+
+// nested reports whether v is a value the flattener expands, and returns it
+// with any pointers removed.
+func nested(v interface{}) (reflect.Value, bool) {
+	// Bounding the number of pointers followed guards against pointer
+	// cycles such as an *interface{} that holds itself.
+	for i := 0; i < defaultFlattenDepth; i++ {
+		switch v.(type) {
+		case nil, string, []byte, time.Time, error, fmt.Stringer, encoding.TextMarshaler, json.Marshaler:
+			return reflect.Value{}, false
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Ptr:
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = rv.Elem().Interface()
+		case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+			return rv, true
+		default:
+			return reflect.Value{}, false
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// mapKeys sorts map keys by their formatted names.
+type mapKeys struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (m mapKeys) Len() int           { return len(m.names) }
+func (m mapKeys) Less(i, j int) bool { return m.names[i] < m.names[j] }
+func (m mapKeys) Swap(i, j int) {
+	m.names[i], m.names[j] = m.names[j], m.names[i]
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+}
+
+type structField struct {
+	name  string
+	index int
+}
+
+// exportedFields returns the exported fields of a struct type, named by
+// their json tag if they have one and omitting those tagged "-".
+func exportedFields(t reflect.Type) []structField {
+	fields := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("json"); ok {
+			tag = strings.Split(tag, ",")[0]
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, structField{name: name, index: i})
+	}
+	return fields
+}
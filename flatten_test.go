@@ -0,0 +1,137 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-logfmt/logfmt"
+)
+
+type flattenRequest struct {
+	Method  string            `json:"method"`
+	Header  map[string]string `json:"header"`
+	Ignored string            `json:"-"`
+	Remote  *flattenAddr
+	private string
+}
+
+type flattenAddr struct {
+	IP   string
+	Port int
+}
+
+var flattenKeyvals = []interface{}{
+	"msg", "handled",
+	"req", flattenRequest{
+		Method:  "GET",
+		Header:  map[string]string{"host": "example.com", "accept": "*/*"},
+		Ignored: "x",
+		Remote:  &flattenAddr{IP: "10.0.0.1", Port: 443},
+		private: "y",
+	},
+	"ids", []int{7, 9},
+	"err", errors.New("not expanded"),
+	"empty", map[string]int{},
+}
+
+func TestLogfmtLoggerFlatten(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewLogfmtLoggerWithOptions(buf, log.LogfmtFlatten(0, "."))
+	if err := logger.Log(flattenKeyvals...); err != nil {
+		t.Fatal(err)
+	}
+	want := `msg=handled req.method=GET req.header.accept=*/* req.header.host=example.com req.Remote.IP=10.0.0.1 req.Remote.Port=443 ids.0=7 ids.1=9 err="not expanded"` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestJSONLoggerFlatten(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewJSONLoggerWithOptions(buf, log.JSONPreserveKeyOrder(), log.JSONFlatten(0, "_"))
+	if err := logger.Log(flattenKeyvals...); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"msg":"handled","req_method":"GET","req_header_accept":"*/*","req_header_host":"example.com","req_Remote_IP":"10.0.0.1","req_Remote_Port":443,"ids_0":7,"ids_1":9,"err":"not expanded"}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+// TestFlattenEquivalence checks that the logfmt and JSON loggers produce the
+// same fields when flattening.
+func TestFlattenEquivalence(t *testing.T) {
+	t.Parallel()
+	logfmtBuf, jsonBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	log.NewLogfmtLoggerWithOptions(logfmtBuf, log.LogfmtFlatten(2, ".")).Log(flattenKeyvals...)
+	log.NewJSONLoggerWithOptions(jsonBuf, log.JSONFlatten(2, ".")).Log(flattenKeyvals...)
+
+	var fromJSON map[string]interface{}
+	if err := json.Unmarshal(jsonBuf.Bytes(), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	fromLogfmt := map[string]string{}
+	dec := logfmt.NewDecoder(logfmtBuf)
+	for dec.ScanRecord() {
+		for dec.ScanKeyval() {
+			fromLogfmt[string(dec.Key())] = string(dec.Value())
+		}
+	}
+	if err := dec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := len(fromJSON), len(fromLogfmt); want != have {
+		t.Errorf("want %d fields, have %d:\n%s%s", want, have, jsonBuf, logfmtBuf)
+	}
+	for k, v := range fromJSON {
+		lv, ok := fromLogfmt[k]
+		if !ok {
+			t.Errorf("logfmt lacks %s", k)
+			continue
+		}
+		if _, isObject := v.(map[string]interface{}); isObject {
+			// Beyond maxDepth JSON keeps the object; logfmt cannot.
+			if want, have := "<unsupported struct>", lv; want != have {
+				t.Errorf("%s: want %q, have %q", k, want, have)
+			}
+			continue
+		}
+		if want, have := fmt.Sprint(v), lv; want != have {
+			t.Errorf("%s: want %q, have %q", k, want, have)
+		}
+	}
+}
+
+func TestFlattenMaxDepth(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewJSONLoggerWithOptions(buf, log.JSONFlatten(1, "."))
+	if err := logger.Log("a", map[string]interface{}{"b": map[string]int{"c": 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"a.b":{"c":1}}`+"\n", buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestFlattenCycle(t *testing.T) {
+	t.Parallel()
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+
+	buf := &bytes.Buffer{}
+	logger := log.NewLogfmtLoggerWithOptions(buf, log.LogfmtFlatten(3, "."))
+	if err := logger.Log("m", cyclic); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `m.self.self.self="<unsupported map>"`+"\n", buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
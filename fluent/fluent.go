@@ -0,0 +1,361 @@
+// Package fluent provides a Logger that ships log events to Fluentd or Fluent
+// Bit using the Forward protocol, in PackedForward mode.
+//
+// Log never blocks on the network: log events are queued and sent in
+// batches from a background goroutine. If sending fails, the connection is
+// closed and the messages of the batch that were not delivered are sent
+// again on a new connection, after a delay that grows exponentially with
+// each failure. Call Close before the program exits to send the queued log
+// events.
+//
+//	client := fluent.NewClient("tcp", "localhost:24224", fluent.Tag("app.checkout"))
+//	defer client.Close()
+//	logger := log.With(client, "ts", log.DefaultTimestampUTC)
+package fluent
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/batch"
+	"github.com/go-kit/log/internal/fields"
+)
+
+// Errors returned by Log.
+var (
+	ErrQueueFull = batch.ErrQueueFull // the queue is full
+	ErrClosed    = batch.ErrClosed    // Close has been called
+)
+
+// eventTimeType is the MessagePack extension type of the Forward protocol's
+// EventTime.
+const eventTimeType = 0
+
+// Client is a Logger that sends log events to a Forward protocol server.
+// See NewClient for details.
+type Client struct {
+	network      string
+	addr         string
+	tag          string
+	tagKey       interface{}
+	ackTimeout   time.Duration
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+	cfg          batch.Config
+	batcher      *batch.Batcher
+	bufPool      sync.Pool
+
+	// conn and dec are only used by the batcher's goroutine, and by Close
+	// after it has stopped.
+	conn net.Conn
+	dec  *log.MsgpackDecoder
+}
+
+// Option sets a parameter for a Client.
+type Option func(*Client)
+
+// Tag sets the tag of log events that have no tag key. The default is "log".
+func Tag(tag string) Option {
+	return func(c *Client) { c.tag = tag }
+}
+
+// TagKey sets the key whose value, if the log event has it, is used as the
+// tag of the log event instead of the default tag. The pair is not included
+// in the record. By default there is no tag key.
+func TagKey(key interface{}) Option {
+	return func(c *Client) { c.tagKey = key }
+}
+
+// Ack enables at-least-once delivery. The server is asked to acknowledge
+// each message, and if it does not within timeout, the message is sent
+// again.
+func Ack(timeout time.Duration) Option {
+	return func(c *Client) { c.ackTimeout = timeout }
+}
+
+// DialTimeout sets the timeout for connecting to the server. The default is
+// 5 seconds.
+func DialTimeout(d time.Duration) Option {
+	return func(c *Client) { c.dialTimeout = d }
+}
+
+// WriteTimeout sets the timeout for writing a message to the server. The
+// default is 10 seconds.
+func WriteTimeout(d time.Duration) Option {
+	return func(c *Client) { c.writeTimeout = d }
+}
+
+// BatchSize sets the maximum number of log events in a message, 500 by
+// default.
+func BatchSize(n int) Option {
+	return batchOption(batch.MaxSize(n))
+}
+
+// FlushInterval sets how often a partial batch is sent, one second by
+// default.
+func FlushInterval(d time.Duration) Option {
+	return batchOption(batch.Interval(d))
+}
+
+// QueueSize sets the maximum number of queued log events, 10000 by default.
+func QueueSize(n int) Option {
+	return batchOption(batch.QueueSize(n))
+}
+
+// Retry sets how failed sends are retried, each on a new connection. By
+// default they are retried 5 times, after delays from 100ms to 10s.
+func Retry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return batchOption(batch.Retry(maxRetries, minBackoff, maxBackoff))
+}
+
+// ErrorHandler sets the function that receives send errors, which are
+// discarded by default.
+func ErrorHandler(f func(error)) Option {
+	return batchOption(batch.ErrorHandler(f))
+}
+
+// batchOption returns an Option that applies o to the batch.Config of the
+// Client.
+func batchOption(o batch.Option) Option {
+	return func(c *Client) { o(&c.cfg) }
+}
+
+// NewClient returns a Client that sends log events to the server at addr on
+// the named network, which is "tcp" or "unix". It connects when it first
+// sends, and starts a background goroutine, which Close stops.
+//
+// Each log event becomes an entry of its tag's message. A time.Time or
+// timestamp Valuer value under "ts" becomes the entry's event time, which is
+// otherwise the time of the Log call. The remaining key/value pairs become
+// the record, encoded as by log.NewMsgpackLogger.
+func NewClient(network, addr string, options ...Option) *Client {
+	c := &Client{
+		network:      network,
+		addr:         addr,
+		tag:          "log",
+		dialTimeout:  5 * time.Second,
+		writeTimeout: 10 * time.Second,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	c.batcher = batch.New(c.send, c.cfg)
+	return c
+}
+
+// entry is a queued log event.
+type entry struct {
+	tag  string
+	data []byte // the msgpack encoded [time, record] array
+	sent bool   // set once the message holding the entry was delivered
+}
+
+type recordBuf struct {
+	buf    bytes.Buffer
+	logger log.Logger
+	kvs    []interface{}
+}
+
+// Log encodes keyvals as an entry and queues it to be sent. It does not
+// block, and returns ErrQueueFull if the queue is full.
+func (c *Client) Log(keyvals ...interface{}) error {
+	rb, _ := c.bufPool.Get().(*recordBuf)
+	if rb == nil {
+		rb = &recordBuf{}
+		rb.logger = log.NewMsgpackLogger(&rb.buf)
+	}
+	defer func() {
+		for i := range rb.kvs {
+			rb.kvs[i] = nil
+		}
+		rb.kvs = rb.kvs[:0]
+		rb.buf.Reset()
+		c.bufPool.Put(rb)
+	}()
+
+	var ts time.Time
+	tag := c.tag
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		if i+1 < len(keyvals) {
+			v := keyvals[i+1]
+			if k == "ts" {
+				if t, ok := fields.Time(v); ok {
+					ts = t
+					continue
+				}
+			}
+			if c.tagKey != nil && k == c.tagKey {
+				tag = fmt.Sprint(v)
+				continue
+			}
+			rb.kvs = append(rb.kvs, k, v)
+			continue
+		}
+		rb.kvs = append(rb.kvs, k)
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	// Encode [time, record], with the time as an EventTime: a fixext 8 of
+	// seconds and nanoseconds.
+	data := make([]byte, 0, 16)
+	sec, nsec := ts.Unix(), ts.Nanosecond()
+	data = append(data, 0x92, 0xd7, eventTimeType,
+		byte(sec>>24), byte(sec>>16), byte(sec>>8), byte(sec),
+		byte(nsec>>24), byte(nsec>>16), byte(nsec>>8), byte(nsec))
+	if err := rb.logger.Log(rb.kvs...); err != nil {
+		return err
+	}
+	data = append(data, rb.buf.Bytes()...)
+	return c.batcher.Add(&entry{tag: tag, data: data})
+}
+
+// Dropped returns the number of log events Log dropped with ErrQueueFull.
+func (c *Client) Dropped() uint64 {
+	return c.batcher.Dropped()
+}
+
+// Flush blocks until the log events passed to Log before it have been sent or
+// given up on. It returns ctx.Err() if ctx is done first.
+func (c *Client) Flush(ctx context.Context) error {
+	return c.batcher.Flush(ctx)
+}
+
+// Close stops accepting log events, blocks until the queued log events have
+// been sent or given up on, and closes the connection.
+func (c *Client) Close() error {
+	err := c.batcher.Shutdown(context.Background())
+	if c.conn != nil {
+		if cerr := c.conn.Close(); err == nil {
+			err = cerr
+		}
+		c.conn = nil
+	}
+	return err
+}
+
+// send sends one batch of entries, as one PackedForward message per tag.
+// When the batch is retried, the messages that were already delivered are
+// not sent again.
+func (c *Client) send(ctx context.Context, items []interface{}) error {
+	var (
+		groups = map[string][]*entry{}
+		order  []string
+	)
+	for _, item := range items {
+		e := item.(*entry)
+		if e.sent {
+			continue
+		}
+		if _, ok := groups[e.tag]; !ok {
+			order = append(order, e.tag)
+		}
+		groups[e.tag] = append(groups[e.tag], e)
+	}
+	for _, tag := range order {
+		if err := c.sendMessage(ctx, tag, groups[tag]); err != nil {
+			if c.conn != nil {
+				c.conn.Close()
+				c.conn, c.dec = nil, nil
+			}
+			return err
+		}
+		for _, e := range groups[tag] {
+			e.sent = true
+		}
+	}
+	return nil
+}
+
+// sendMessage sends [tag, entries, option], where entries is a bin holding
+// the concatenated entries.
+func (c *Client) sendMessage(ctx context.Context, tag string, entries []*entry) error {
+	if c.conn == nil {
+		d := net.Dialer{Timeout: c.dialTimeout}
+		conn, err := d.DialContext(ctx, c.network, c.addr)
+		if err != nil {
+			return fmt.Errorf("fluent: %w", err)
+		}
+		c.conn, c.dec = conn, log.NewMsgpackDecoder(conn)
+	}
+
+	size := 0
+	for _, e := range entries {
+		size += len(e.data)
+	}
+	if uint64(size) > math.MaxUint32 {
+		return batch.Permanent(errors.New("fluent: message too large"))
+	}
+	msg := make([]byte, 0, size+len(tag)+64)
+	msg = append(msg, 0x93)
+	msg = appendString(msg, tag)
+	msg = append(msg, 0xc6, byte(size>>24), byte(size>>16), byte(size>>8), byte(size))
+	for _, e := range entries {
+		msg = append(msg, e.data...)
+	}
+	option := []interface{}{"size", len(entries)}
+	var chunk string
+	if c.ackTimeout > 0 {
+		chunk = newChunkID()
+		option = append(option, "chunk", chunk)
+	}
+	var opt bytes.Buffer
+	log.NewMsgpackLogger(&opt).Log(option...)
+	msg = append(msg, opt.Bytes()...)
+
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	if _, err := c.conn.Write(msg); err != nil {
+		return fmt.Errorf("fluent: %w", err)
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.ackTimeout))
+	resp, err := c.dec.Decode()
+	if err != nil {
+		return fmt.Errorf("fluent: reading ack: %w", err)
+	}
+	for i := 0; i+1 < len(resp); i += 2 {
+		if resp[i] == "ack" && resp[i+1] == chunk {
+			return nil
+		}
+	}
+	return fmt.Errorf("fluent: unexpected ack response %v", resp)
+}
+
+// appendString appends s as a msgpack string.
+func appendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, s...)
+}
+
+// newChunkID returns a random chunk ID for ack mode.
+func newChunkID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return base64.StdEncoding.EncodeToString(b[:])
+}
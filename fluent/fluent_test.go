@@ -0,0 +1,285 @@
+package fluent_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/fluent"
+)
+
+// message is a decoded PackedForward message.
+type message struct {
+	tag     string
+	entries [][]interface{}
+	option  map[string]interface{}
+}
+
+// server is an in-process stand-in for a Forward protocol server. It
+// acknowledges messages that ask for it, except that it closes the first
+// dropAcks connections without acknowledging, as well as the connection of
+// the dropMessage-th message received if dropMessage is set.
+type server struct {
+	ln          net.Listener
+	mu          sync.Mutex
+	messages    []message
+	conns       int
+	dropAcks    int
+	dropMessage int
+	received    chan struct{}
+}
+
+func newServer(t *testing.T, network, addr string, dropAcks int) *server {
+	t.Helper()
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{ln: ln, dropAcks: dropAcks, received: make(chan struct{}, 100)}
+	go s.serve(t)
+	return s
+}
+
+func (s *server) serve(t *testing.T) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		drop := s.conns <= s.dropAcks
+		s.mu.Unlock()
+		go s.handle(t, conn, drop)
+	}
+}
+
+func (s *server) handle(t *testing.T, conn net.Conn, drop bool) {
+	defer conn.Close()
+	dec := log.NewMsgpackDecoder(conn)
+	for {
+		v, err := dec.DecodeValue()
+		if err != nil {
+			return
+		}
+		msg, err := decodeMessage(v)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, msg)
+		drop := drop || len(s.messages) == s.dropMessage
+		s.mu.Unlock()
+		s.received <- struct{}{}
+		if chunk, ok := msg.option["chunk"]; ok {
+			if drop {
+				return
+			}
+			log.NewMsgpackLogger(conn).Log("ack", chunk)
+		}
+	}
+}
+
+func decodeMessage(v interface{}) (message, error) {
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 3 {
+		return message{}, fmt.Errorf("want [tag, entries, option], have %#v", v)
+	}
+	msg := message{tag: a[0].(string), option: a[2].(map[string]interface{})}
+	dec := log.NewMsgpackDecoder(bytes.NewReader(a[1].([]byte)))
+	for {
+		e, err := dec.DecodeValue()
+		if err == io.EOF {
+			return msg, nil
+		}
+		if err != nil {
+			return message{}, err
+		}
+		msg.entries = append(msg.entries, e.([]interface{}))
+	}
+}
+
+func (s *server) result() []message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]message(nil), s.messages...)
+}
+
+func eventTime(t time.Time) log.MsgpackExt {
+	sec, nsec := uint32(t.Unix()), uint32(t.Nanosecond())
+	return log.MsgpackExt{Type: 0, Data: []byte{
+		byte(sec >> 24), byte(sec >> 16), byte(sec >> 8), byte(sec),
+		byte(nsec >> 24), byte(nsec >> 16), byte(nsec >> 8), byte(nsec),
+	}}
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	s := newServer(t, "tcp", "127.0.0.1:0", 0)
+	defer s.ln.Close()
+
+	client := fluent.NewClient("tcp", s.ln.Addr().String(),
+		fluent.Tag("app"),
+		fluent.TagKey("component"),
+		fluent.FlushInterval(time.Hour),
+	)
+	ts := time.Unix(1700000000, 123)
+	logger := log.With(client, "ts", log.TimestampFormat(func() time.Time { return ts }, time.RFC3339))
+	logger.Log("msg", "one", "n", 1)
+	logger.Log("component", "db", "msg", "two")
+	logger.Log("msg", "three")
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []message{
+		{
+			tag: "app",
+			entries: [][]interface{}{
+				{eventTime(ts), map[string]interface{}{"msg": "one", "n": uint64(1)}},
+				{eventTime(ts), map[string]interface{}{"msg": "three"}},
+			},
+			option: map[string]interface{}{"size": uint64(2)},
+		},
+		{
+			tag: "db",
+			entries: [][]interface{}{
+				{eventTime(ts), map[string]interface{}{"msg": "two"}},
+			},
+			option: map[string]interface{}{"size": uint64(1)},
+		},
+	}
+	waitFor(t, s, len(want))
+	if have := s.result(); !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+}
+
+func TestClientUnix(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("no unix sockets")
+	}
+	s := newServer(t, "unix", filepath.Join(t.TempDir(), "fluent.sock"), 0)
+	defer s.ln.Close()
+
+	client := fluent.NewClient("unix", s.ln.Addr().String())
+	client.Log("msg", "hello")
+	client.Close()
+	waitFor(t, s, 1)
+	if have := s.result(); len(have) != 1 || have[0].tag != "log" {
+		t.Errorf("have %#v", have)
+	}
+}
+
+func TestClientAck(t *testing.T) {
+	t.Parallel()
+	// The first connection is closed without an ack, so the message is sent
+	// again on a new connection.
+	s := newServer(t, "tcp", "127.0.0.1:0", 1)
+	defer s.ln.Close()
+
+	var errs []error
+	client := fluent.NewClient("tcp", s.ln.Addr().String(),
+		fluent.Ack(5*time.Second),
+		fluent.Retry(3, time.Millisecond, time.Millisecond),
+		fluent.FlushInterval(time.Hour),
+		fluent.ErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	client.Log("msg", "acked")
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+
+	have := s.result()
+	if len(have) != 2 {
+		t.Fatalf("want message sent twice, have %#v", have)
+	}
+	if have[0].option["chunk"] == have[1].option["chunk"] {
+		t.Error("want a new chunk ID for the new attempt")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if want, have := 2, s.conns; want != have {
+		t.Errorf("want %d connections, have %d", want, have)
+	}
+}
+
+func TestClientAckPartial(t *testing.T) {
+	t.Parallel()
+	// The message of the second tag is not acknowledged, so only that one
+	// is sent again.
+	s := newServer(t, "tcp", "127.0.0.1:0", 0)
+	defer s.ln.Close()
+	s.mu.Lock()
+	s.dropMessage = 2
+	s.mu.Unlock()
+
+	client := fluent.NewClient("tcp", s.ln.Addr().String(),
+		fluent.TagKey("component"),
+		fluent.Ack(5*time.Second),
+		fluent.Retry(3, time.Millisecond, time.Millisecond),
+		fluent.FlushInterval(time.Hour),
+	)
+	client.Log("component", "a", "msg", "one")
+	client.Log("component", "b", "msg", "two")
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var tags []string
+	for _, msg := range s.result() {
+		tags = append(tags, msg.tag)
+	}
+	if want := []string{"a", "b", "b"}; !reflect.DeepEqual(want, tags) {
+		t.Errorf("want tags %v, have %v", want, tags)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	t.Parallel()
+	// Nothing listens on the address at first.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	client := fluent.NewClient("tcp", addr,
+		fluent.Retry(100, 5*time.Millisecond, 20*time.Millisecond),
+		fluent.FlushInterval(time.Hour),
+	)
+	client.Log("msg", "eventually")
+	go client.Flush(context.Background())
+	time.Sleep(30 * time.Millisecond)
+
+	s := newServer(t, "tcp", addr, 0)
+	defer s.ln.Close()
+	waitFor(t, s, 1)
+	client.Close()
+}
+
+func waitFor(t *testing.T, s *server, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d messages", i, n)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package gelf provides a Logger that encodes log events in the Graylog
+// Extended Log Format (GELF) 1.1, and writers that ship GELF messages to a
+// Graylog server over UDP or TCP.
+//
+// The Logger and writers are independent: the Logger writes each message
+// with one call to Write, so it can write to a UDPWriter, a TCPWriter, or any
+// other io.Writer.
+//
+//	w, err := gelf.NewUDPWriter("graylog.example.com:12201",
+//		gelf.UDPCompress(gzip.BestSpeed))
+//	if err != nil {
+//		// handle error
+//	}
+//	logger := gelf.NewLogger(w)
+//	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+package gelf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/fields"
+	"github.com/go-kit/log/level"
+)
+
+// GELF severities, which are the syslog severities.
+const (
+	severityEmergency = 0
+	severityAlert     = 1
+	severityCritical  = 2
+	severityError     = 3
+	severityWarning   = 4
+	severityNotice    = 5
+	severityInfo      = 6
+	severityDebug     = 7
+)
+
+// severities maps levels to GELF severities.
+var severities = map[fields.Level]int{
+	fields.LevelTrace:     severityDebug,
+	fields.LevelDebug:     severityDebug,
+	fields.LevelInfo:      severityInfo,
+	fields.LevelNotice:    severityNotice,
+	fields.LevelWarn:      severityWarning,
+	fields.LevelError:     severityError,
+	fields.LevelCritical:  severityCritical,
+	fields.LevelAlert:     severityAlert,
+	fields.LevelFatal:     severityCritical,
+	fields.LevelEmergency: severityEmergency,
+}
+
+// NewLogger returns a Logger that encodes each log event as a GELF 1.1
+// message and writes it to w with one call to w.Write. The passed Writer must
+// be safe for concurrent use by multiple goroutines if the returned Logger
+// will be used concurrently.
+//
+// Key/value pairs are mapped to GELF fields as follows. The "msg" value,
+// formatted as a string, becomes short_message. GELF requires a non-empty
+// short_message, so it is "-" if the log event has no message. A level.Value
+// or a level name such as "warning" or "crit" under level.Key() becomes
+// level, the matching syslog severity; log events without one have the
+// informational severity. A time.Time or timestamp Valuer value under "ts"
+// becomes timestamp, which is otherwise the time of the Log call. All other
+// pairs become additional fields, named by their key prefixed with an
+// underscore, with characters GELF does not allow in field names replaced by
+// underscores. The key "id" becomes _id_, since GELF reserves _id. Additional
+// field values are numbers if they are Go numbers and strings otherwise.
+func NewLogger(w io.Writer, options ...Option) log.Logger {
+	l := &logger{w: w}
+	for _, option := range options {
+		option(l)
+	}
+	if l.host == "" {
+		l.host, _ = os.Hostname()
+		if l.host == "" {
+			l.host = "localhost"
+		}
+	}
+	return l
+}
+
+// Option sets a parameter for the Logger returned by NewLogger.
+type Option func(*logger)
+
+// Host sets the host field of every message. By default it is the host name
+// reported by the operating system.
+func Host(name string) Option {
+	return func(l *logger) { l.host = name }
+}
+
+type logger struct {
+	w    io.Writer
+	host string
+	pool sync.Pool
+}
+
+// loggerBuf holds a JSON logger and the buffer it writes to.
+type loggerBuf struct {
+	buf     bytes.Buffer
+	json    log.Logger
+	keyvals []interface{}
+}
+
+func (l *logger) getLoggerBuf() *loggerBuf {
+	lb, _ := l.pool.Get().(*loggerBuf)
+	if lb == nil {
+		lb = &loggerBuf{}
+		lb.json = log.NewOrderedJSONLogger(&lb.buf)
+	} else {
+		lb.buf.Reset()
+	}
+	return lb
+}
+
+func (l *logger) putLoggerBuf(lb *loggerBuf) {
+	for i := range lb.keyvals {
+		lb.keyvals[i] = nil
+	}
+	lb.keyvals = lb.keyvals[:0]
+	l.pool.Put(lb)
+}
+
+func (l *logger) Log(keyvals ...interface{}) error {
+	var (
+		message   = "-"
+		severity  = severityInfo
+		timestamp time.Time
+	)
+	lb := l.getLoggerBuf()
+	defer l.putLoggerBuf(lb)
+
+	// The extras are collected after the standard fields in lb.keyvals.
+	kvs := append(lb.keyvals, "version", "1.1", "host", l.host, "short_message", nil, "timestamp", nil, "level", nil)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		switch {
+		case k == "msg":
+			if message = fmt.Sprint(v); message == "" {
+				message = "-"
+			}
+			continue
+		case k == level.Key():
+			if sev, ok := levelSeverity(v); ok {
+				severity = sev
+				continue
+			}
+		case k == "ts":
+			if t, ok := fields.Time(v); ok {
+				timestamp = t
+				continue
+			}
+		}
+		kvs = append(kvs, fieldName(k), fieldValue(v))
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	kvs[5] = message
+	kvs[7] = float64(timestamp.UnixNano()/int64(time.Millisecond)) / 1e3
+	kvs[9] = severity
+	lb.keyvals = kvs
+
+	if err := lb.json.Log(kvs...); err != nil {
+		return err
+	}
+	// Strip the newline the JSON logger appends; each Write is one message.
+	_, err := l.w.Write(bytes.TrimSuffix(lb.buf.Bytes(), []byte("\n")))
+	return err
+}
+
+// levelSeverity returns the severity of the level value v.
+func levelSeverity(v interface{}) (int, bool) {
+	l, ok := fields.ParseLevel(v)
+	if !ok {
+		return 0, false
+	}
+	return severities[l], true
+}
+
+var invalidFieldChars = regexp.MustCompile(`[^\w.\-]`)
+
+// fieldName returns the name of the additional field for key k.
+func fieldName(k interface{}) string {
+	name, ok := k.(string)
+	if !ok {
+		name = fmt.Sprint(k)
+	}
+	if name == "id" {
+		return "_id_"
+	}
+	return "_" + invalidFieldChars.ReplaceAllString(name, "_")
+}
+
+// fieldValue returns v as a number or a string, the only additional field
+// value types GELF allows.
+func fieldValue(v interface{}) interface{} {
+	switch v.(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,116 @@
+package gelf_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/gelf"
+	"github.com/go-kit/log/level"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := gelf.NewLogger(buf, gelf.Host("web-1"))
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	logger = log.With(logger, "ts", log.TimestampFormat(func() time.Time { return ts }, time.RFC3339))
+
+	if err := level.Warn(logger).Log("msg", "disk low", "free", 0.05, "id", 7, "http status", 503, "err", errors.New("boom"), "ok", true); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		t.Errorf("message ends with a newline: %q", buf)
+	}
+	want := `{"version":"1.1","host":"web-1","short_message":"disk low","timestamp":1714979289.123,"level":4,"_free":0.05,"_id_":7,"_http_status":503,"_err":"boom","_ok":"true"}`
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestLoggerDefaults(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	before := time.Now()
+	if err := gelf.NewLogger(buf).Log("a", "b", "level", "custom"); err != nil {
+		t.Fatal(err)
+	}
+	var have map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatal(err)
+	}
+	if want := "-"; have["short_message"] != want {
+		t.Errorf("short_message: want %q, have %v", want, have["short_message"])
+	}
+	if want := 6.0; have["level"] != want {
+		t.Errorf("level: want %v, have %v", want, have["level"])
+	}
+	// A level that is not a level.Value is an additional field.
+	if want := "custom"; have["_level"] != want {
+		t.Errorf("_level: want %v, have %v", want, have["_level"])
+	}
+	if ts, _ := have["timestamp"].(float64); ts < float64(before.Unix()) {
+		t.Errorf("timestamp: want at least %d, have %v", before.Unix(), have["timestamp"])
+	}
+	if host, _ := have["host"].(string); host == "" {
+		t.Error("host is empty")
+	}
+}
+
+func TestLoggerLevelNames(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		level     interface{}
+		wantLevel float64
+		wantExtra interface{}
+	}{
+		{"error", 3, nil},
+		{"ERR", 3, nil},
+		{"warning", 4, nil},
+		{"notice", 5, nil},
+		{"crit", 2, nil},
+		{"emergency", 0, nil},
+		{"debug", 7, nil},
+		{"verbose", 6, "verbose"},
+		{42, 6, float64(42)},
+	} {
+		buf := &bytes.Buffer{}
+		gelf.NewLogger(buf).Log("level", test.level, "msg", "x")
+		var have map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+			t.Fatal(err)
+		}
+		if test.wantLevel != have["level"] {
+			t.Errorf("%v: want level %v, have %v", test.level, test.wantLevel, have["level"])
+		}
+		if test.wantExtra != have["_level"] {
+			t.Errorf("%v: want _level %v, have %v", test.level, test.wantExtra, have["_level"])
+		}
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		logger func(log.Logger) log.Logger
+		want   float64
+	}{
+		{level.Debug, 7},
+		{level.Info, 6},
+		{level.Warn, 4},
+		{level.Error, 3},
+	} {
+		buf := &bytes.Buffer{}
+		test.logger(gelf.NewLogger(buf)).Log("msg", "x")
+		var have struct{ Level float64 }
+		if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+			t.Fatal(err)
+		}
+		if test.want != have.Level {
+			t.Errorf("want level %v, have %v", test.want, have.Level)
+		}
+	}
+}
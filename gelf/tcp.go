@@ -0,0 +1,115 @@
+package gelf
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultDialTimeout is the default timeout for connecting to a GELF TCP
+// input.
+const DefaultDialTimeout = 5 * time.Second
+
+// TCPWriter sends each message written to it to a GELF TCP input, terminated
+// by a null byte. If the connection fails, the TCPWriter reconnects, once
+// during the failed Write and otherwise on the next Write. A TCPWriter is
+// safe for concurrent use by multiple goroutines.
+type TCPWriter struct {
+	addr         string
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// TCPOption sets a parameter for a TCPWriter.
+type TCPOption func(*TCPWriter)
+
+// TCPDialTimeout sets the timeout for connecting to the server. The default
+// is DefaultDialTimeout.
+func TCPDialTimeout(d time.Duration) TCPOption {
+	return func(w *TCPWriter) { w.dialTimeout = d }
+}
+
+// TCPWriteTimeout sets the timeout for writing a message to the server. By
+// default there is none.
+func TCPWriteTimeout(d time.Duration) TCPOption {
+	return func(w *TCPWriter) { w.writeTimeout = d }
+}
+
+// NewTCPWriter returns a TCPWriter connected to addr, a host:port address.
+func NewTCPWriter(addr string, options ...TCPOption) (*TCPWriter, error) {
+	w := &TCPWriter{addr: addr, dialTimeout: DefaultDialTimeout}
+	for _, option := range options {
+		option(w)
+	}
+	conn, err := net.DialTimeout("tcp", addr, w.dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+var errNullByte = errors.New("gelf: message contains a null byte")
+
+// Write sends p as one GELF message. p must not contain a null byte, which
+// would end the message early; the Logger returned by NewLogger never
+// produces one.
+func (w *TCPWriter) Write(p []byte) (int, error) {
+	if bytes.IndexByte(p, 0) >= 0 {
+		return 0, errNullByte
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.write(p)
+	if err != nil && w.conn != nil {
+		// The connection may have been closed by the server since the last
+		// write, so retry on a new connection.
+		w.conn.Close()
+		w.conn = nil
+		err = w.write(p)
+	}
+	if err != nil {
+		if w.conn != nil {
+			w.conn.Close()
+			w.conn = nil
+		}
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *TCPWriter) write(p []byte) error {
+	if w.conn == nil {
+		conn, err := net.DialTimeout("tcp", w.addr, w.dialTimeout)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+	if w.writeTimeout > 0 {
+		if err := w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout)); err != nil {
+			return err
+		}
+	}
+	bufs := net.Buffers{p, []byte{0}}
+	_, err := bufs.WriteTo(w.conn)
+	return err
+}
+
+// Close closes the connection to the server. A later Write reconnects.
+func (w *TCPWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,158 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+)
+
+const (
+	// DefaultChunkSize is the default maximum size of a UDP datagram. It
+	// suits most networks, including those with a reduced MTU.
+	DefaultChunkSize = 1420
+
+	chunkHeaderSize = 12
+	maxChunks       = 128
+)
+
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// ErrMessageTooLarge is returned by UDPWriter.Write for a message that does
+// not fit in the 128 chunks GELF allows.
+var ErrMessageTooLarge = errors.New("gelf: message too large")
+
+// UDPWriter sends each message written to it to a GELF UDP input. Messages
+// larger than a datagram are split into GELF chunks. A UDPWriter is safe for
+// concurrent use by multiple goroutines.
+type UDPWriter struct {
+	conn      net.Conn
+	chunkSize int
+	compress  bool
+	level     int
+	bufPool   sync.Pool
+}
+
+// UDPOption sets a parameter for a UDPWriter.
+type UDPOption func(*UDPWriter)
+
+// UDPChunkSize sets the maximum size of the datagrams sent, including the
+// chunk header. The default is DefaultChunkSize. Graylog recommends up to
+// 8192 on networks that support jumbo frames.
+func UDPChunkSize(n int) UDPOption {
+	return func(w *UDPWriter) { w.chunkSize = n }
+}
+
+// UDPCompress enables gzip compression of messages at the given level,
+// which is one of the levels accepted by gzip.NewWriterLevel. Messages are
+// not compressed by default.
+func UDPCompress(level int) UDPOption {
+	return func(w *UDPWriter) {
+		w.compress = true
+		w.level = level
+	}
+}
+
+// NewUDPWriter returns a UDPWriter that sends messages to addr, a host:port
+// address.
+func NewUDPWriter(addr string, options ...UDPOption) (*UDPWriter, error) {
+	w := &UDPWriter{chunkSize: DefaultChunkSize}
+	for _, option := range options {
+		option(w)
+	}
+	if w.chunkSize <= chunkHeaderSize {
+		return nil, errors.New("gelf: chunk size too small")
+	}
+	if w.compress {
+		// Check the level here so that Write cannot fail on it.
+		if _, err := gzip.NewWriterLevel(nil, w.level); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+type udpBuf struct {
+	compressed bytes.Buffer
+	gz         *gzip.Writer
+	chunk      []byte
+}
+
+// Write sends p as one GELF message.
+func (w *UDPWriter) Write(p []byte) (int, error) {
+	buf, _ := w.bufPool.Get().(*udpBuf)
+	if buf == nil {
+		buf = &udpBuf{}
+	}
+	defer w.bufPool.Put(buf)
+
+	msg := p
+	if w.compress {
+		buf.compressed.Reset()
+		if buf.gz == nil {
+			buf.gz, _ = gzip.NewWriterLevel(&buf.compressed, w.level)
+		} else {
+			buf.gz.Reset(&buf.compressed)
+		}
+		if _, err := buf.gz.Write(p); err != nil {
+			return 0, err
+		}
+		if err := buf.gz.Close(); err != nil {
+			return 0, err
+		}
+		msg = buf.compressed.Bytes()
+	}
+
+	if len(msg) <= w.chunkSize {
+		if _, err := w.conn.Write(msg); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if err := w.writeChunks(buf, msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeChunks sends msg split into GELF chunks, each prefixed by a header
+// holding the chunk magic bytes, a message ID shared by all the chunks, the
+// sequence number and the number of chunks.
+func (w *UDPWriter) writeChunks(buf *udpBuf, msg []byte) error {
+	dataSize := w.chunkSize - chunkHeaderSize
+	count := (len(msg) + dataSize - 1) / dataSize
+	if count > maxChunks {
+		return ErrMessageTooLarge
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		data := msg[i*dataSize:]
+		if len(data) > dataSize {
+			data = data[:dataSize]
+		}
+		chunk := append(buf.chunk[:0], chunkMagic...)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data...)
+		buf.chunk = chunk
+		if _, err := w.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying connection.
+func (w *UDPWriter) Close() error {
+	return w.conn.Close()
+}
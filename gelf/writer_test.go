@@ -0,0 +1,181 @@
+package gelf_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log/gelf"
+)
+
+func TestUDPWriter(t *testing.T) {
+	t.Parallel()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := gelf.NewUDPWriter(conn.LocalAddr().String(), gelf.UDPChunkSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	small := `{"short_message":"small"}`
+	if _, err := w.Write([]byte(small)); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := small, string(readDatagram(t, conn)); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	large := `{"short_message":"` + strings.Repeat("x", 1000) + `"}`
+	if _, err := w.Write([]byte(large)); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := large, string(readChunked(t, conn)); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	if _, err := w.Write(make([]byte, 100*128)); err != gelf.ErrMessageTooLarge {
+		t.Errorf("want ErrMessageTooLarge, have %v", err)
+	}
+}
+
+func TestUDPWriterCompress(t *testing.T) {
+	t.Parallel()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := gelf.NewUDPWriter(conn.LocalAddr().String(), gelf.UDPChunkSize(64), gelf.UDPCompress(gzip.NoCompression))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	msg := `{"short_message":"` + strings.Repeat("y", 200) + `"}`
+	if _, err := w.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(readChunked(t, conn)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := msg; want != string(have) {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	if _, err := gelf.NewUDPWriter(conn.LocalAddr().String(), gelf.UDPCompress(42)); err == nil {
+		t.Error("want error for invalid compression level")
+	}
+}
+
+func readDatagram(t *testing.T, conn net.PacketConn) []byte {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[:n]
+}
+
+// readChunked reads and reassembles one chunked GELF message.
+func readChunked(t *testing.T, conn net.PacketConn) []byte {
+	t.Helper()
+	var (
+		id     []byte
+		chunks [][]byte
+		seen   int
+	)
+	for chunks == nil || seen < len(chunks) {
+		d := readDatagram(t, conn)
+		if len(d) < 12 || d[0] != 0x1e || d[1] != 0x0f {
+			t.Fatalf("not a GELF chunk: %x", d)
+		}
+		if id == nil {
+			id = append([]byte(nil), d[2:10]...)
+			chunks = make([][]byte, d[11])
+		}
+		if !bytes.Equal(id, d[2:10]) {
+			t.Fatalf("chunk message ID %x, want %x", d[2:10], id)
+		}
+		if int(d[11]) != len(chunks) || int(d[10]) >= len(chunks) {
+			t.Fatalf("bad chunk sequence %d/%d", d[10], d[11])
+		}
+		chunks[d[10]] = d[12:]
+		seen++
+	}
+	return bytes.Join(chunks, nil)
+}
+
+func TestTCPWriter(t *testing.T) {
+	t.Parallel()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := make(chan string)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := r.ReadString(0)
+					if err != nil {
+						return
+					}
+					messages <- msg
+				}
+			}()
+		}
+	}()
+
+	w, err := gelf.NewTCPWriter(ln.Addr().String(), gelf.TCPWriteTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for _, msg := range []string{`{"a":1}`, `{"b":2}`} {
+		if _, err := w.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := msg+"\x00", <-messages; want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+
+	// Writing after Close reconnects.
+	w.Close()
+	if _, err := w.Write([]byte(`{"c":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"c":3}`+"\x00", <-messages; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	if _, err := w.Write([]byte("a\x00b")); err == nil {
+		t.Error("want error for message with null byte")
+	}
+}
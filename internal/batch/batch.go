@@ -0,0 +1,271 @@
+// Package batch collects items into batches and delivers them from a
+// background goroutine, retrying failed deliveries with exponential backoff.
+// It is shared by the packages that ship log events to remote collectors.
+package batch
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/log/internal/drain"
+)
+
+// ErrQueueFull is returned by Add when the queue has no room for the item.
+var ErrQueueFull = errors.New("queue full")
+
+// ErrClosed is returned by Add after Shutdown has been called.
+var ErrClosed = errors.New("batcher shut down")
+
+// Config holds the parameters of a Batcher. Zero fields take their defaults.
+type Config struct {
+	// MaxSize is the maximum number of items in a batch. The default is 500.
+	MaxSize int
+
+	// Interval is how often a partial batch is sent. The default is one
+	// second.
+	Interval time.Duration
+
+	// QueueSize is the maximum number of items that may wait to be batched.
+	// Add drops items that arrive when the queue is full. The default is
+	// 10000.
+	QueueSize int
+
+	// MaxRetries is the number of times a failed delivery is retried. The
+	// default is 5; a negative value disables retries.
+	MaxRetries int
+
+	// MinBackoff and MaxBackoff bound the delay before a retry, which
+	// doubles with each attempt. The defaults are 100ms and 10s.
+	MinBackoff, MaxBackoff time.Duration
+
+	// ErrorHandler is called with the error of each batch that could not be
+	// delivered, and for each item dropped because the queue was full. By
+	// default those errors are discarded.
+	ErrorHandler func(error)
+}
+
+// SendFunc delivers a batch of items. Errors are retried unless they are
+// wrapped with Permanent. The context is canceled if Shutdown gives up on
+// pending deliveries.
+type SendFunc func(ctx context.Context, items []interface{}) error
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err to indicate that the delivery it describes must not be
+// retried, such as when the server rejected the batch as malformed.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Batcher queues items and delivers them in batches. It is safe for
+// concurrent use by multiple goroutines.
+type Batcher struct {
+	// Accessed atomically, kept first for 64-bit alignment on 32-bit
+	// platforms.
+	dropped uint64
+	drain   drain.Tracker
+
+	send   SendFunc
+	cfg    Config
+	queue  chan interface{}
+	kick   chan struct{}
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards closed and prevents Shutdown from closing queue while a call
+	// to Add is sending on it.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New returns a Batcher that delivers batches with send, and starts its
+// background goroutine. Call Shutdown to stop it.
+func New(send SendFunc, cfg Config) *Batcher {
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 500
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 10 * time.Second
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Batcher{
+		send:   send,
+		cfg:    cfg,
+		queue:  make(chan interface{}, cfg.QueueSize),
+		kick:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go b.run()
+	return b
+}
+
+// Add queues item for delivery without blocking. It returns ErrQueueFull if
+// the item was dropped, and ErrClosed after Shutdown.
+func (b *Batcher) Add(item interface{}) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+	select {
+	case b.queue <- item:
+		b.drain.Submit()
+		return nil
+	default:
+		atomic.AddUint64(&b.dropped, 1)
+		b.handleError(ErrQueueFull)
+		return ErrQueueFull
+	}
+}
+
+// Dropped returns the number of items dropped because the queue was full.
+func (b *Batcher) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// Flush sends the items added before the call to Flush without waiting for
+// the interval, and blocks until they have been delivered or given up on, or
+// until ctx is done. It returns ctx.Err() if ctx is done first.
+func (b *Batcher) Flush(ctx context.Context) error {
+	target := b.drain.Submitted()
+	select {
+	case b.kick <- struct{}{}:
+	default: // a flush is already pending
+	}
+	return b.drain.Wait(ctx, target)
+}
+
+// Shutdown stops accepting items and blocks until the queued items have been
+// delivered or given up on. If ctx is done first, Shutdown cancels the
+// pending deliveries and returns ctx.Err().
+func (b *Batcher) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		<-b.done
+		return ctx.Err()
+	}
+}
+
+func (b *Batcher) run() {
+	defer close(b.done)
+	defer b.cancel()
+	ticker := time.NewTicker(b.cfg.Interval)
+	defer ticker.Stop()
+
+	var pending []interface{}
+	for {
+		select {
+		case item, ok := <-b.queue:
+			if !ok {
+				b.sendAll(pending)
+				return
+			}
+			pending = append(pending, item)
+			if len(pending) >= b.cfg.MaxSize {
+				pending = b.sendAll(pending)
+			}
+		case <-ticker.C:
+			pending = b.sendAll(pending)
+		case <-b.kick:
+			// Take everything queued before the flush was requested.
+			for n := len(b.queue); n > 0; n-- {
+				item, ok := <-b.queue
+				if !ok {
+					break
+				}
+				pending = append(pending, item)
+			}
+			pending = b.sendAll(pending)
+		}
+	}
+}
+
+// sendAll delivers pending in batches of at most MaxSize items and returns
+// pending emptied for reuse.
+func (b *Batcher) sendAll(pending []interface{}) []interface{} {
+	for len(pending) > 0 {
+		n := len(pending)
+		if n > b.cfg.MaxSize {
+			n = b.cfg.MaxSize
+		}
+		items := make([]interface{}, n)
+		copy(items, pending)
+		pending = pending[n:]
+		if err := b.deliver(items); err != nil {
+			b.handleError(err)
+		}
+		b.drain.Complete(uint64(n))
+	}
+	return pending[:0]
+}
+
+// deliver sends items, retrying with backoff.
+func (b *Batcher) deliver(items []interface{}) error {
+	backoff := b.cfg.MinBackoff
+	for attempt := 0; ; attempt++ {
+		err := b.send(b.ctx, items)
+		if err == nil {
+			return nil
+		}
+		var perm permanentError
+		if errors.As(err, &perm) || attempt >= b.cfg.MaxRetries || b.ctx.Err() != nil {
+			return err
+		}
+		// Wait between half and all of the backoff so that clients that
+		// failed together do not retry together.
+		d := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-b.ctx.Done():
+			t.Stop()
+			return err
+		}
+		if backoff *= 2; backoff > b.cfg.MaxBackoff {
+			backoff = b.cfg.MaxBackoff
+		}
+	}
+}
+
+func (b *Batcher) handleError(err error) {
+	if b.cfg.ErrorHandler != nil {
+		b.cfg.ErrorHandler(err)
+	}
+}
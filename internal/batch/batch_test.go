@@ -0,0 +1,221 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log/internal/batch"
+)
+
+// recorder is a SendFunc that records the batches it receives and fails
+// with the errors in fail, in order, before succeeding.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]interface{}
+	calls   int
+	fail    []error
+}
+
+func (r *recorder) send(ctx context.Context, items []interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if len(r.fail) > 0 {
+		err := r.fail[0]
+		r.fail = r.fail[1:]
+		return err
+	}
+	r.batches = append(r.batches, items)
+	return nil
+}
+
+func (r *recorder) result() ([][]interface{}, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.batches, r.calls
+}
+
+func TestBatcherSize(t *testing.T) {
+	t.Parallel()
+	r := &recorder{}
+	b := batch.New(r.send, batch.Config{MaxSize: 2, Interval: time.Hour})
+	for i := 0; i < 5; i++ {
+		if err := b.Add(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	batches, _ := r.result()
+	if want := [][]interface{}{{0, 1}, {2, 3}, {4}}; !reflect.DeepEqual(want, batches) {
+		t.Errorf("want %v, have %v", want, batches)
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := batch.ErrClosed, b.Add(5); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestBatcherInterval(t *testing.T) {
+	t.Parallel()
+	r := &recorder{}
+	b := batch.New(r.send, batch.Config{Interval: 10 * time.Millisecond})
+	defer b.Shutdown(context.Background())
+	b.Add("x")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if batches, _ := r.result(); len(batches) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("batch not sent after interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBatcherRetry(t *testing.T) {
+	t.Parallel()
+	transient, permanent := errors.New("transient"), errors.New("permanent")
+	r := &recorder{fail: []error{transient, transient, batch.Permanent(permanent)}}
+	var (
+		mu     sync.Mutex
+		failed []error
+	)
+	b := batch.New(r.send, batch.Config{
+		Interval:   time.Hour,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+		ErrorHandler: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, err)
+		},
+	})
+	defer b.Shutdown(context.Background())
+
+	b.Add(1)
+	b.Flush(context.Background())
+	if _, calls := r.result(); calls != 3 {
+		t.Errorf("want 3 attempts, have %d", calls)
+	}
+	mu.Lock()
+	if len(failed) != 1 || !errors.Is(failed[0], permanent) {
+		t.Errorf("want permanent error reported, have %v", failed)
+	}
+	mu.Unlock()
+
+	// Later items are delivered.
+	b.Add(2)
+	b.Flush(context.Background())
+	if batches, _ := r.result(); !reflect.DeepEqual([][]interface{}{{2}}, batches) {
+		t.Errorf("have %v", batches)
+	}
+}
+
+func TestBatcherMaxRetries(t *testing.T) {
+	t.Parallel()
+	err := errors.New("down")
+	r := &recorder{fail: []error{err, err, err, err}}
+	b := batch.New(r.send, batch.Config{Interval: time.Hour, MaxRetries: 2, MinBackoff: time.Millisecond})
+	defer b.Shutdown(context.Background())
+	b.Add(1)
+	b.Flush(context.Background())
+	if _, calls := r.result(); calls != 3 {
+		t.Errorf("want 3 attempts, have %d", calls)
+	}
+}
+
+func TestBatcherQueueFull(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	send := func(ctx context.Context, items []interface{}) error {
+		<-release
+		return nil
+	}
+	b := batch.New(send, batch.Config{MaxSize: 1, QueueSize: 1, Interval: time.Hour})
+	// The first item is taken by the blocked send, the second fills the
+	// queue, and the third is dropped, though it may take a few tries for
+	// the background goroutine to take the first.
+	var dropped int
+	for i := 0; i < 100 && dropped == 0; i++ {
+		if err := b.Add(i); err == batch.ErrQueueFull {
+			dropped++
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if dropped == 0 || b.Dropped() == 0 {
+		t.Errorf("want dropped items, have %d", b.Dropped())
+	}
+	close(release)
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatcherShutdown(t *testing.T) {
+	t.Parallel()
+	r := &recorder{}
+	b := batch.New(r.send, batch.Config{Interval: time.Hour})
+	b.Add(1)
+	b.Add(2)
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if batches, _ := r.result(); !reflect.DeepEqual([][]interface{}{{1, 2}}, batches) {
+		t.Errorf("have %v", batches)
+	}
+}
+
+func TestBatcherShutdownTimeout(t *testing.T) {
+	t.Parallel()
+	send := func(ctx context.Context, items []interface{}) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	b := batch.New(send, batch.Config{Interval: time.Hour})
+	b.Add(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if want, have := context.DeadlineExceeded, b.Shutdown(ctx); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	t.Parallel()
+	var cfg batch.Config
+	for _, option := range []batch.Option{
+		batch.MaxSize(10),
+		batch.Interval(time.Minute),
+		batch.QueueSize(20),
+		batch.Retry(-1, time.Millisecond, time.Second),
+	} {
+		option(&cfg)
+	}
+	want := batch.Config{
+		MaxSize:    10,
+		Interval:   time.Minute,
+		QueueSize:  20,
+		MaxRetries: -1,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: time.Second,
+	}
+	if !reflect.DeepEqual(want, cfg) {
+		t.Errorf("\nwant %+v\nhave %+v", want, cfg)
+	}
+
+	var called bool
+	batch.ErrorHandler(func(error) { called = true })(&cfg)
+	cfg.ErrorHandler(nil)
+	if !called {
+		t.Error("ErrorHandler not set")
+	}
+}
@@ -0,0 +1,43 @@
+package batch
+
+import "time"
+
+// Option sets a field of a Config. The packages built on a Batcher wrap
+// these in their own option types, so that the behavior of the shared
+// options is implemented and documented here once; see Config for the
+// defaults.
+type Option func(*Config)
+
+// MaxSize sets the maximum number of items in a batch.
+func MaxSize(n int) Option {
+	return func(c *Config) { c.MaxSize = n }
+}
+
+// Interval sets how often a partial batch is sent.
+func Interval(d time.Duration) Option {
+	return func(c *Config) { c.Interval = d }
+}
+
+// QueueSize sets the maximum number of items that may wait to be batched.
+// Add drops items that arrive when the queue is full.
+func QueueSize(n int) Option {
+	return func(c *Config) { c.QueueSize = n }
+}
+
+// Retry sets the number of times a failed delivery is retried, and the
+// bounds of the delay before a retry, which doubles with each attempt. A
+// negative maxRetries disables retries.
+func Retry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Config) {
+		c.MaxRetries = maxRetries
+		c.MinBackoff = minBackoff
+		c.MaxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets a function that is called with the error of each batch
+// that could not be delivered after all retries, and with ErrQueueFull for
+// each dropped item.
+func ErrorHandler(f func(error)) Option {
+	return func(c *Config) { c.ErrorHandler = f }
+}
@@ -0,0 +1,80 @@
+// Package drain tracks the items handed to a background goroutine so that
+// callers can wait for those submitted so far to be processed. It is shared
+// by log.AsyncLogger and the batch package, which differ in how they queue
+// and process items but flush them the same way.
+package drain
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Tracker counts submitted and completed items. The zero value is ready to
+// use. A Tracker must be 64-bit aligned on 32-bit platforms, so it should be
+// the first field of a struct or follow other 64-bit fields.
+type Tracker struct {
+	// Accessed atomically, kept first for 64-bit alignment on 32-bit
+	// platforms.
+	submitted uint64
+
+	mu        sync.Mutex
+	completed uint64
+	waiters   []waiter
+}
+
+type waiter struct {
+	target uint64
+	c      chan struct{}
+}
+
+// Submit records that one more item has been submitted.
+func (t *Tracker) Submit() {
+	atomic.AddUint64(&t.submitted, 1)
+}
+
+// Submitted returns the number of items submitted so far, to be passed to
+// Wait.
+func (t *Tracker) Submitted() uint64 {
+	return atomic.LoadUint64(&t.submitted)
+}
+
+// Complete records that n more items have been processed, or given up on,
+// and releases the Wait calls waiting for them.
+func (t *Tracker) Complete(n uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.completed += n
+	if len(t.waiters) == 0 {
+		return
+	}
+	waiters := t.waiters[:0]
+	for _, w := range t.waiters {
+		if w.target <= t.completed {
+			close(w.c)
+			continue
+		}
+		waiters = append(waiters, w)
+	}
+	t.waiters = waiters
+}
+
+// Wait blocks until target items have completed, or until ctx is done. It
+// returns ctx.Err() if ctx is done first.
+func (t *Tracker) Wait(ctx context.Context, target uint64) error {
+	t.mu.Lock()
+	if t.completed >= target {
+		t.mu.Unlock()
+		return nil
+	}
+	c := make(chan struct{})
+	t.waiters = append(t.waiters, waiter{target: target, c: c})
+	t.mu.Unlock()
+
+	select {
+	case <-c:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,46 @@
+package drain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log/internal/drain"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+	var tr drain.Tracker
+	if err := tr.Wait(context.Background(), tr.Submitted()); err != nil {
+		t.Fatalf("nothing submitted: %v", err)
+	}
+
+	tr.Submit()
+	tr.Submit()
+	target := tr.Submitted()
+	tr.Submit() // submitted after the target, so not waited for
+
+	done := make(chan error, 1)
+	go func() { done <- tr.Wait(context.Background(), target) }()
+	tr.Complete(1)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v before the items completed", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	tr.Complete(1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTrackerContext(t *testing.T) {
+	t.Parallel()
+	var tr drain.Tracker
+	tr.Submit()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if want, have := context.DeadlineExceeded, tr.Wait(ctx, tr.Submitted()); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
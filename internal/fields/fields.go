@@ -0,0 +1,40 @@
+// Package fields interprets the values of the conventional keys of log
+// events. It is shared by the packages that map log events to other formats.
+package fields
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time returns the time held by v, a time.Time or a timestamp Valuer value.
+func Time(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case interface{ Time() time.Time }:
+		return t.Time(), true
+	}
+	return time.Time{}, false
+}
+
+// SplitCaller splits a "file:line" caller value, such as one produced by
+// log.Caller. Values other than strings are formatted with fmt.Sprint first,
+// so that a caller recorded as a fmt.Stringer is split too.
+func SplitCaller(v interface{}) (file string, line int, ok bool) {
+	s, isString := v.(string)
+	if !isString {
+		s = fmt.Sprint(v)
+	}
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return s[:i], line, true
+}
@@ -0,0 +1,86 @@
+package fields_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/fields"
+	"github.com/go-kit/log/level"
+)
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+func TestTime(t *testing.T) {
+	t.Parallel()
+	want := time.Date(2023, 5, 17, 14, 3, 27, 0, time.UTC)
+	valuer := log.TimestampFormat(func() time.Time { return want }, time.RFC3339)
+	for _, v := range []interface{}{want, valuer()} {
+		if have, ok := fields.Time(v); !ok || !have.Equal(want) {
+			t.Errorf("%v: want %v, have %v %v", v, want, have, ok)
+		}
+	}
+	if _, ok := fields.Time("2023-05-17T14:03:27Z"); ok {
+		t.Error("string: want not ok")
+	}
+}
+
+func TestSplitCaller(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		v    interface{}
+		file string
+		line int
+		ok   bool
+	}{
+		{"main.go:42", "main.go", 42, true},
+		{"c:/src/main.go:7", "c:/src/main.go", 7, true},
+		{stringer("server.go:3"), "server.go", 3, true},
+		{"main.go", "", 0, false},
+		{"main.go:x", "", 0, false},
+		{nil, "", 0, false},
+	} {
+		file, line, ok := fields.SplitCaller(test.v)
+		if file != test.file || line != test.line || ok != test.ok {
+			t.Errorf("%v: want %q %d %v, have %q %d %v", test.v, test.file, test.line, test.ok, file, line, ok)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+	var nilPtr *ptrStringer
+	for _, test := range []struct {
+		v    interface{}
+		want fields.Level
+		ok   bool
+	}{
+		{level.WarnValue(), fields.LevelWarn, true},
+		{"WARNING", fields.LevelWarn, true},
+		{"err", fields.LevelError, true},
+		{"crit", fields.LevelCritical, true},
+		{"fatal", fields.LevelFatal, true},
+		{"panic", fields.LevelEmergency, true},
+		{stringer("Notice"), fields.LevelNotice, true},
+		{"verbose", 0, false},
+		{42, 0, false},
+		{nilPtr, 0, false},
+	} {
+		have, ok := fields.ParseLevel(test.v)
+		if have != test.want || ok != test.ok {
+			t.Errorf("%v: want %v %v, have %v %v", test.v, test.want, test.ok, have, ok)
+		}
+	}
+	if want, have := "critical", fields.LevelCritical.String(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+// ptrStringer is a fmt.Stringer whose String method panics on a nil pointer.
+type ptrStringer struct {
+	name string
+}
+
+func (s *ptrStringer) String() string { return s.name }
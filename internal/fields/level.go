@@ -0,0 +1,77 @@
+package fields
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is one of the log levels that the packages mapping log events to
+// other formats recognize. Each of them maps a Level to its own severity.
+type Level int
+
+// The levels, from least to most severe.
+const (
+	LevelTrace Level = iota + 1
+	LevelDebug
+	LevelInfo
+	LevelNotice
+	LevelWarn
+	LevelError
+	LevelCritical
+	LevelAlert
+	LevelFatal
+	LevelEmergency
+)
+
+var levelNames = [...]string{
+	LevelTrace:     "trace",
+	LevelDebug:     "debug",
+	LevelInfo:      "info",
+	LevelNotice:    "notice",
+	LevelWarn:      "warn",
+	LevelError:     "error",
+	LevelCritical:  "critical",
+	LevelAlert:     "alert",
+	LevelFatal:     "fatal",
+	LevelEmergency: "emergency",
+}
+
+// levels maps the names of package level, the syslog severities and their
+// common abbreviations, in lower case, to levels.
+var levels = map[string]Level{
+	"trace":     LevelTrace,
+	"debug":     LevelDebug,
+	"info":      LevelInfo,
+	"notice":    LevelNotice,
+	"warn":      LevelWarn,
+	"warning":   LevelWarn,
+	"error":     LevelError,
+	"err":       LevelError,
+	"critical":  LevelCritical,
+	"crit":      LevelCritical,
+	"alert":     LevelAlert,
+	"fatal":     LevelFatal,
+	"emergency": LevelEmergency,
+	"emerg":     LevelEmergency,
+	"panic":     LevelEmergency,
+}
+
+// ParseLevel returns the Level named by the level value v, such as a
+// level.Value or a string like "WARNING", compared without regard to case.
+// Values other than strings are formatted with fmt.Sprint first.
+func ParseLevel(v interface{}) (Level, bool) {
+	name, ok := v.(string)
+	if !ok {
+		name = fmt.Sprint(v)
+	}
+	l, ok := levels[strings.ToLower(name)]
+	return l, ok
+}
+
+// String returns the name of l, such as "warn".
+func (l Level) String() string {
+	if l <= 0 || int(l) >= len(levelNames) {
+		return ""
+	}
+	return levelNames[l]
+}
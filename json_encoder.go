@@ -0,0 +1,248 @@
+package log
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// jsonEncoder streams JSON into a reusable buffer. It writes the common
+// value types directly and falls back to encoding/json for the rest.
+type jsonEncoder struct {
+	b        []byte
+	fields   []jsonField
+	fallback bytes.Buffer
+	enc      *json.Encoder
+	errs     []string
+}
+
+var jsonEncoderPool = sync.Pool{
+	New: func() interface{} {
+		var enc jsonEncoder
+		enc.enc = json.NewEncoder(&enc.fallback)
+		enc.enc.SetEscapeHTML(false)
+		return &enc
+	},
+}
+
+// maxPooledJSONBuffer limits the size of the buffers kept in jsonEncoderPool
+// so that one very large log event does not pin its memory indefinitely.
+const maxPooledJSONBuffer = 64 << 10
+
+func getJSONEncoder() *jsonEncoder {
+	return jsonEncoderPool.Get().(*jsonEncoder)
+}
+
+func putJSONEncoder(enc *jsonEncoder) {
+	if cap(enc.b) > maxPooledJSONBuffer || enc.fallback.Cap() > maxPooledJSONBuffer {
+		return
+	}
+	enc.b = enc.b[:0]
+	// Release the values so the pool does not keep them reachable.
+	for i := range enc.fields {
+		enc.fields[i] = jsonField{}
+	}
+	enc.fields = enc.fields[:0]
+	enc.fallback.Reset()
+	jsonEncoderPool.Put(enc)
+}
+
+// encodeObject appends a JSON object holding fields to enc.b. Values that
+// cannot be encoded are replaced with a string describing the error. If
+// errKey is not empty and any value was replaced, a field named errKey
+// listing the errors is added to the end of the object.
+func (enc *jsonEncoder) encodeObject(fields []jsonField, errKey string) {
+	enc.errs = enc.errs[:0]
+	enc.b = append(enc.b, '{')
+	for i, f := range fields {
+		if i > 0 {
+			enc.b = append(enc.b, ',')
+		}
+		enc.b = appendJSONString(enc.b, f.key)
+		enc.b = append(enc.b, ':')
+		enc.encodeField(f)
+	}
+	enc.endObject(len(fields) > 0, errKey)
+}
+
+// endObject adds the errKey field, if needed, and closes the object.
+func (enc *jsonEncoder) endObject(hasFields bool, errKey string) {
+	if errKey != "" && len(enc.errs) > 0 {
+		if hasFields {
+			enc.b = append(enc.b, ',')
+		}
+		enc.b = appendJSONString(enc.b, errKey)
+		enc.b = append(enc.b, ':')
+		enc.b = appendJSONString(enc.b, strings.Join(enc.errs, "; "))
+	}
+	enc.b = append(enc.b, '}')
+}
+
+// encodeField appends the value of f to enc.b, or a description of the
+// error if the value cannot be encoded, so that one bad value does not cost
+// the whole log event.
+func (enc *jsonEncoder) encodeField(f jsonField) {
+	n := len(enc.b)
+	if err := enc.encodeValue(f.value); err != nil {
+		enc.b = appendJSONString(enc.b[:n], "ENCODE ERROR: "+err.Error())
+		enc.errs = append(enc.errs, f.key+": "+err.Error())
+	}
+}
+
+// encodeValue appends the JSON encoding of v to enc.b. As with encoding/json,
+// json.Marshaler and encoding.TextMarshaler take priority over the Error and
+// String methods; those are used instead of the default encoding of errors
+// and fmt.Stringers, which would often be an empty object.
+func (enc *jsonEncoder) encodeValue(v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		enc.b = append(enc.b, "null"...)
+	case string:
+		enc.b = appendJSONString(enc.b, x)
+	case bool:
+		enc.b = strconv.AppendBool(enc.b, x)
+	case int:
+		enc.b = strconv.AppendInt(enc.b, int64(x), 10)
+	case int8:
+		enc.b = strconv.AppendInt(enc.b, int64(x), 10)
+	case int16:
+		enc.b = strconv.AppendInt(enc.b, int64(x), 10)
+	case int32:
+		enc.b = strconv.AppendInt(enc.b, int64(x), 10)
+	case int64:
+		enc.b = strconv.AppendInt(enc.b, x, 10)
+	case uint:
+		enc.b = strconv.AppendUint(enc.b, uint64(x), 10)
+	case uint8:
+		enc.b = strconv.AppendUint(enc.b, uint64(x), 10)
+	case uint16:
+		enc.b = strconv.AppendUint(enc.b, uint64(x), 10)
+	case uint32:
+		enc.b = strconv.AppendUint(enc.b, uint64(x), 10)
+	case uint64:
+		enc.b = strconv.AppendUint(enc.b, x, 10)
+	case float32:
+		return enc.encodeFloat(float64(x), 32)
+	case float64:
+		return enc.encodeFloat(x, 64)
+	case time.Time:
+		// Time.MarshalJSON rejects years it cannot format as RFC 3339.
+		if y := x.Year(); y < 0 || y > 9999 {
+			return enc.encodeFallback(v)
+		}
+		enc.b = append(enc.b, '"')
+		enc.b = x.AppendFormat(enc.b, time.RFC3339Nano)
+		enc.b = append(enc.b, '"')
+	case json.Marshaler, encoding.TextMarshaler:
+		return enc.encodeFallback(v)
+	case error:
+		return enc.encodeValue(safeError(x))
+	case fmt.Stringer:
+		enc.b = appendJSONString(enc.b, safeString(x))
+	default:
+		return enc.encodeFallback(v)
+	}
+	return nil
+}
+
+// encodeFloat appends f formatted the way encoding/json formats floats.
+func (enc *jsonEncoder) encodeFloat(f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return &json.UnsupportedValueError{
+			Value: reflect.ValueOf(f),
+			Str:   strconv.FormatFloat(f, 'g', -1, bits),
+		}
+	}
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	enc.b = strconv.AppendFloat(enc.b, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(enc.b); n >= 4 && enc.b[n-4] == 'e' && enc.b[n-3] == '-' && enc.b[n-2] == '0' {
+			enc.b[n-2] = enc.b[n-1]
+			enc.b = enc.b[:n-1]
+		}
+	}
+	return nil
+}
+
+// encodeFallback appends the encoding/json encoding of v.
+func (enc *jsonEncoder) encodeFallback(v interface{}) error {
+	enc.fallback.Reset()
+	if err := enc.enc.Encode(v); err != nil {
+		return err
+	}
+	out := enc.fallback.Bytes()
+	enc.b = append(enc.b, out[:len(out)-1]...) // Encode appends a newline
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a JSON string, escaped as encoding/json does
+// with HTML escaping disabled.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but not valid JavaScript, so
+		// encoding/json escapes them.
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,56 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// TestJSONLoggerMatchesEncodingJSON checks that the values the JSON logger
+// encodes itself come out as encoding/json would encode them.
+func TestJSONLoggerMatchesEncodingJSON(t *testing.T) {
+	t.Parallel()
+	values := []interface{}{
+		nil,
+		"",
+		"plain",
+		"quote\" backslash\\ newline\n return\r tab\t",
+		"control\x00\x01\x1f",
+		"unicode \u00e9\u4e16 \u2028\u2029",
+		"invalid \xff utf-8",
+		"<html> & stuff",
+		true,
+		false,
+		int(-1), int8(-8), int16(-16), int32(-32), int64(math.MinInt64),
+		uint(1), uint8(8), uint16(16), uint32(32), uint64(math.MaxUint64),
+		0.0, -0.0, 1.5, -2.25, 1e20, 1e21, 1e-6, 1e-7, 123456789.125, math.MaxFloat64, math.SmallestNonzeroFloat64,
+		float32(0.1), float32(1e21), float32(1e-7), float32(math.MaxFloat32),
+		time.Date(2006, 1, 2, 15, 4, 5, 999999999, time.FixedZone("", -7*60*60)),
+		time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		[]int{1, 2, 3},
+		map[string]int{"b": 2, "a": 1},
+		struct{ A string }{"a"},
+		[]byte("bytes"),
+	}
+
+	for _, v := range values {
+		buf := &bytes.Buffer{}
+		if err := log.NewJSONLogger(buf).Log("v", v); err != nil {
+			t.Fatalf("%#v: %v", v, err)
+		}
+
+		want := &bytes.Buffer{}
+		enc := json.NewEncoder(want)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(map[string]interface{}{"v": v}); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := want.String(), buf.String(); want != have {
+			t.Errorf("%#v:\nwant %s\nhave %s", v, want, have)
+		}
+	}
+}
@@ -1,65 +1,209 @@
 package log
 
 import (
-	"encoding"
-	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 )
 
 type jsonLogger struct {
 	io.Writer
+	preserveOrder bool
+	duplicates    DuplicateKeyPolicy
+	errKey        string
+	flatten       *flattener
+	nestSep       string
 }
 
 // NewJSONLogger returns a Logger that encodes keyvals to the Writer as a
 // single JSON object. Each log event produces no more than one call to
 // w.Write. The passed Writer must be safe for concurrent use by multiple
 // goroutines if the returned Logger will be used concurrently.
+//
+// The keys of the JSON object are sorted. If a key occurs more than once the
+// last value wins. Values that cannot be encoded as JSON, such as NaN, a
+// channel, or a cyclic data structure, are replaced with a string describing
+// the error, so that the rest of the log event is still written.
 func NewJSONLogger(w io.Writer) Logger {
-	return &jsonLogger{w}
+	return &jsonLogger{Writer: w}
 }
 
+// NewOrderedJSONLogger returns a Logger like the one returned by
+// NewJSONLogger, except that it writes the keys of the JSON object in the
+// order they appear in keyvals, so that keys placed first with WithPrefix
+// are written first.
+func NewOrderedJSONLogger(w io.Writer) Logger {
+	return NewJSONLoggerWithOptions(w, JSONPreserveKeyOrder())
+}
+
+// NewJSONLoggerWithOptions returns a Logger like the one returned by
+// NewJSONLogger, configured by options.
+func NewJSONLoggerWithOptions(w io.Writer, options ...JSONOption) Logger {
+	l := &jsonLogger{Writer: w}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// JSONOption sets a parameter for the Logger returned by
+// NewJSONLoggerWithOptions.
+type JSONOption func(*jsonLogger)
+
+// JSONPreserveKeyOrder writes the keys of each JSON object in the order they
+// appear in keyvals rather than sorted.
+func JSONPreserveKeyOrder() JSONOption {
+	return func(l *jsonLogger) { l.preserveOrder = true }
+}
+
+// JSONDuplicateKeys sets how keys that occur more than once in keyvals are
+// written. The default is DuplicateKeysLastWins.
+func JSONDuplicateKeys(p DuplicateKeyPolicy) JSONOption {
+	return func(l *jsonLogger) { l.duplicates = p }
+}
+
+// JSONEncodeErrorKey adds a field named key to each JSON object in which a
+// value could not be encoded. The field is written last and lists the keys
+// of the replaced values with their errors. The default of "" adds no field;
+// "_encode_error" is a conventional choice.
+func JSONEncodeErrorKey(key string) JSONOption {
+	return func(l *jsonLogger) { l.errKey = key }
+}
+
+// JSONFlatten expands maps, structs, slices and arrays into one field per
+// leaf value, with the keys along the path to the leaf joined by separator.
+// It produces the same fields as LogfmtFlatten; see there for details.
+func JSONFlatten(maxDepth int, separator string) JSONOption {
+	return func(l *jsonLogger) { l.flatten = newFlattener(maxDepth, separator) }
+}
+
+// JSONNested expands keys containing separator into nested objects, so that
+// the keys "http.method" and "http.status" produce
+//
+//	{"http":{"method":"GET","status":200}}
+//
+// when separator is ".". If a key names both a value and an object, as
+// "http" and "http.method" do, the object wins and the value is written in
+// it under the key "_value", whatever the order of the keys in keyvals.
+// Keys with an empty segment, such as "a..b", ".x" or "y.", are not
+// expanded. Duplicate keys are resolved on the full key before nesting. When
+// key order is not preserved the keys of every object are sorted.
+func JSONNested(separator string) JSONOption {
+	return func(l *jsonLogger) { l.nestSep = separator }
+}
+
+// DuplicateKeyPolicy determines how a JSON Logger writes keys that occur
+// more than once in keyvals.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysLastWins writes a key once, with the value of its last
+	// occurrence. When key order is preserved the key is written at the
+	// position of its first occurrence.
+	DuplicateKeysLastWins DuplicateKeyPolicy = iota
+
+	// DuplicateKeysFirstWins writes a key once, with the value of its first
+	// occurrence.
+	DuplicateKeysFirstWins
+
+	// DuplicateKeysKeepAll writes every occurrence of a key. The result is
+	// valid JSON, but many decoders keep only one of the values.
+	DuplicateKeysKeepAll
+)
+
 func (l *jsonLogger) Log(keyvals ...interface{}) error {
-	n := (len(keyvals) + 1) / 2 // +1 to handle case when len is odd
-	m := make(map[string]interface{}, n)
+	enc := getJSONEncoder()
+	defer putJSONEncoder(enc)
+
+	if l.flatten != nil {
+		keyvals = l.flatten.keyvals(keyvals)
+	}
+
+	enc.fields = l.appendFields(enc.fields, keyvals)
+	if l.nestSep != "" {
+		enc.encodeNested(l.nest(enc.fields), l.errKey)
+	} else {
+		enc.encodeObject(enc.fields, l.errKey)
+	}
+	enc.b = append(enc.b, '\n')
+
+	// The Logger interface requires implementations to be safe for concurrent
+	// use by multiple goroutines. For this implementation that means making
+	// only one call to l.Writer.Write() for each call to Log.
+	_, err := l.Writer.Write(enc.b)
+	return err
+}
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// appendFields appends the fields of the JSON object for keyvals to fields
+// in the order they are to be written, with duplicate keys resolved.
+func (l *jsonLogger) appendFields(fields []jsonField, keyvals []interface{}) []jsonField {
 	for i := 0; i < len(keyvals); i += 2 {
-		k := keyvals[i]
 		var v interface{} = ErrMissingValue
 		if i+1 < len(keyvals) {
 			v = keyvals[i+1]
 		}
-		merge(m, k, v)
+		f := jsonField{key: jsonKey(keyvals[i]), value: v}
+		if l.duplicates != DuplicateKeysKeepAll {
+			if j := indexJSONField(fields, f.key); j >= 0 {
+				if l.duplicates == DuplicateKeysLastWins {
+					fields[j].value = f.value
+				}
+				continue
+			}
+		}
+		fields = append(fields, f)
+	}
+	if !l.preserveOrder {
+		sortJSONFields(fields)
+	}
+	return fields
+}
+
+func indexJSONField(fields []jsonField, key string) int {
+	for i, f := range fields {
+		if f.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortJSONFields sorts fields by key, keeping fields with equal keys in
+// their original order.
+func sortJSONFields(fields []jsonField) {
+	if len(fields) > 12 {
+		sort.Stable(jsonFields(fields))
+		return
+	}
+	// Insertion sort avoids allocating for the common case of a few fields.
+	for i := 1; i < len(fields); i++ {
+		for j := i; j > 0 && fields[j].key < fields[j-1].key; j-- {
+			fields[j], fields[j-1] = fields[j-1], fields[j]
+		}
 	}
-	enc := json.NewEncoder(l.Writer)
-	enc.SetEscapeHTML(false)
-	return enc.Encode(m)
 }
 
-func merge(dst map[string]interface{}, k, v interface{}) {
-	var key string
+type jsonFields []jsonField
+
+func (f jsonFields) Len() int           { return len(f) }
+func (f jsonFields) Less(i, j int) bool { return f[i].key < f[j].key }
+func (f jsonFields) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
+func jsonKey(k interface{}) string {
 	switch x := k.(type) {
 	case string:
-		key = x
+		return x
 	case fmt.Stringer:
-		key = safeString(x)
+		return safeString(x)
 	default:
-		key = fmt.Sprint(x)
-	}
-
-	// We want json.Marshaler and encoding.TextMarshaller to take priority over
-	// err.Error() and v.String(). But json.Marshall (called later) does that by
-	// default so we force a no-op if it's one of those 2 case.
-	switch x := v.(type) {
-	case json.Marshaler:
-	case encoding.TextMarshaler:
-	case error:
-		v = safeError(x)
-	case fmt.Stringer:
-		v = safeString(x)
+		return fmt.Sprint(x)
 	}
-
-	dst[key] = v
 }
 
 func safeString(str fmt.Stringer) (s string) {
@@ -2,8 +2,11 @@ package log_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/go-kit/log"
@@ -18,7 +21,7 @@ func TestJSONLoggerCaller(t *testing.T) {
 	if err := logger.Log(); err != nil {
 		t.Fatal(err)
 	}
-	if want, have := `{"caller":"json_logger_test.go:18"}`+"\n", buf.String(); want != have {
+	if want, have := `{"caller":"json_logger_test.go:21"}`+"\n", buf.String(); want != have {
 		t.Errorf("\nwant %#v\nhave %#v", want, have)
 	}
 }
@@ -111,6 +114,76 @@ func TestJSONLoggerNoHTMLEscape(t *testing.T) {
 	}
 }
 
+func TestOrderedJSONLogger(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewOrderedJSONLogger(buf)
+	logger = log.WithPrefix(logger, "ts", "now", "level", "info")
+	if err := logger.Log("msg", "hello", "err", errors.New("err"), "a", []int{1, 2}, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"ts":"now","level":"info","msg":"hello","err":"err","a":[1,2],"k":"(MISSING)"}`+"\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+}
+
+func TestJSONLoggerDuplicateKeys(t *testing.T) {
+	t.Parallel()
+	keyvals := []interface{}{"b", 1, "a", 2, "b", 3}
+	tests := []struct {
+		options []log.JSONOption
+		want    string
+	}{
+		{
+			options: nil,
+			want:    `{"a":2,"b":3}`,
+		},
+		{
+			options: []log.JSONOption{log.JSONDuplicateKeys(log.DuplicateKeysFirstWins)},
+			want:    `{"a":2,"b":1}`,
+		},
+		{
+			options: []log.JSONOption{log.JSONDuplicateKeys(log.DuplicateKeysKeepAll)},
+			want:    `{"a":2,"b":1,"b":3}`,
+		},
+		{
+			options: []log.JSONOption{log.JSONPreserveKeyOrder()},
+			want:    `{"b":3,"a":2}`,
+		},
+		{
+			options: []log.JSONOption{log.JSONPreserveKeyOrder(), log.JSONDuplicateKeys(log.DuplicateKeysFirstWins)},
+			want:    `{"b":1,"a":2}`,
+		},
+		{
+			options: []log.JSONOption{log.JSONPreserveKeyOrder(), log.JSONDuplicateKeys(log.DuplicateKeysKeepAll)},
+			want:    `{"b":1,"a":2,"b":3}`,
+		},
+	}
+
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		logger := log.NewJSONLoggerWithOptions(buf, test.options...)
+		if err := logger.Log(keyvals...); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := test.want+"\n", buf.String(); want != have {
+			t.Errorf("\nwant %#v\nhave %#v", want, have)
+		}
+	}
+}
+
+func TestOrderedJSONLoggerNoHTMLEscape(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewOrderedJSONLogger(buf)
+	if err := logger.Log("<k>", "<&>"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"<k>":"<&>"}`+"\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave%#v", want, have)
+	}
+}
+
 // aller implements json.Marshaler, encoding.TextMarshaler, and fmt.Stringer.
 type aller struct{}
 
@@ -210,3 +283,74 @@ func TestJSONLoggerConcurrency(t *testing.T) {
 	t.Parallel()
 	testConcurrency(t, log.NewJSONLogger(ioutil.Discard), 10000)
 }
+
+func BenchmarkJSONLoggerTyped(b *testing.B) {
+	benchmarkRunner(b, log.NewJSONLogger(ioutil.Discard), typedMessage)
+}
+
+func BenchmarkOrderedJSONLoggerSimple(b *testing.B) {
+	benchmarkRunner(b, log.NewOrderedJSONLogger(ioutil.Discard), baseMessage)
+}
+
+func BenchmarkOrderedJSONLoggerTyped(b *testing.B) {
+	benchmarkRunner(b, log.NewOrderedJSONLogger(ioutil.Discard), typedMessage)
+}
+
+func TestOrderedJSONLoggerConcurrency(t *testing.T) {
+	t.Parallel()
+	testConcurrency(t, log.NewOrderedJSONLogger(ioutil.Discard), 10000)
+}
+
+func TestJSONLoggerUnencodableValues(t *testing.T) {
+	t.Parallel()
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+
+	buf := &bytes.Buffer{}
+	logger := log.NewOrderedJSONLogger(buf)
+	if err := logger.Log("msg", "still here", "nan", math.NaN(), "ch", make(chan int), "fn", func() {}, "cyclic", cyclic); err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("%v: %s", err, buf.Bytes())
+	}
+	if want, have := "still here", m["msg"]; want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+	for _, k := range []string{"nan", "ch", "fn", "cyclic"} {
+		s, _ := m[k].(string)
+		if want, have := "ENCODE ERROR: json: unsupported ", s; !strings.HasPrefix(have, want) {
+			t.Errorf("%s: want prefix %q, have %q", k, want, have)
+		}
+	}
+	if want, have := `"nan":"ENCODE ERROR: json: unsupported value: NaN"`, buf.String(); !strings.Contains(have, want) {
+		t.Errorf("want %s in %s", want, have)
+	}
+	if _, ok := m["_encode_error"]; ok {
+		t.Errorf("unexpected _encode_error in %s", buf.Bytes())
+	}
+}
+
+func TestJSONLoggerEncodeErrorKey(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewJSONLoggerWithOptions(buf, log.JSONEncodeErrorKey("_encode_error"))
+
+	if err := logger.Log("b", math.Inf(1), "a", 1); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"a":1,"b":"ENCODE ERROR: json: unsupported value: +Inf","_encode_error":"b: json: unsupported value: +Inf"}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+
+	buf.Reset()
+	if err := logger.Log("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"a":1}`+"\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"sort"
+	"strings"
+)
+
+// nestedValueKey is the key under which a value is written when its key
+// also names an object.
+const nestedValueKey = "_value"
+
+// jsonNode is a member of a nested JSON object. It holds a value, an object,
+// or both when the keys conflict.
+type jsonNode struct {
+	name     string
+	field    jsonField // the value with its full key
+	hasValue bool
+	children []*jsonNode
+}
+
+// nest arranges fields into a tree of objects by splitting their keys on
+// l.nestSep. Keys with an empty segment are not split.
+func (l *jsonLogger) nest(fields []jsonField) []*jsonNode {
+	var root jsonNode
+	for _, f := range fields {
+		parent := &root
+		names := strings.Split(f.key, l.nestSep)
+		if hasEmpty(names) {
+			names = []string{f.key}
+		}
+		for i, name := range names {
+			last := i == len(names)-1
+			n := parent.child(name)
+			if n == nil || last && n.hasValue {
+				// A value for a key already holding one only happens with
+				// DuplicateKeysKeepAll, so keep both.
+				n = &jsonNode{name: name}
+				parent.children = append(parent.children, n)
+			}
+			if last {
+				n.field, n.hasValue = f, true
+			}
+			parent = n
+		}
+	}
+	if !l.preserveOrder {
+		root.sort()
+	}
+	return root.children
+}
+
+// hasEmpty reports whether any of names is empty, as when a key starts or
+// ends with the separator or contains it twice in a row.
+func hasEmpty(names []string) bool {
+	for _, name := range names {
+		if name == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *jsonNode) child(name string) *jsonNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *jsonNode) sort() {
+	sort.SliceStable(n.children, func(i, j int) bool { return n.children[i].name < n.children[j].name })
+	for _, c := range n.children {
+		c.sort()
+	}
+}
+
+// encodeNested appends a JSON object holding nodes to enc.b, handling errors
+// as encodeObject does.
+func (enc *jsonEncoder) encodeNested(nodes []*jsonNode, errKey string) {
+	enc.errs = enc.errs[:0]
+	enc.b = append(enc.b, '{')
+	enc.encodeNodes(nodes)
+	enc.endObject(len(nodes) > 0, errKey)
+}
+
+func (enc *jsonEncoder) encodeNodes(nodes []*jsonNode) {
+	for i, n := range nodes {
+		if i > 0 {
+			enc.b = append(enc.b, ',')
+		}
+		enc.b = appendJSONString(enc.b, n.name)
+		enc.b = append(enc.b, ':')
+		if len(n.children) == 0 {
+			enc.encodeField(n.field)
+			continue
+		}
+		enc.b = append(enc.b, '{')
+		if n.hasValue {
+			enc.b = appendJSONString(enc.b, nestedValueKey)
+			enc.b = append(enc.b, ':')
+			enc.encodeField(n.field)
+			enc.b = append(enc.b, ',')
+		}
+		enc.encodeNodes(n.children)
+		enc.b = append(enc.b, '}')
+	}
+}
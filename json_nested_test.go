@@ -0,0 +1,112 @@
+package log_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestJSONLoggerNested(t *testing.T) {
+	t.Parallel()
+	keyvals := []interface{}{"msg", "served", "http.status", 200, "http.method", "GET", "http.url.path", "/"}
+	tests := []struct {
+		options []log.JSONOption
+		want    string
+	}{
+		{
+			options: []log.JSONOption{log.JSONNested(".")},
+			want:    `{"http":{"method":"GET","status":200,"url":{"path":"/"}},"msg":"served"}`,
+		},
+		{
+			options: []log.JSONOption{log.JSONNested("."), log.JSONPreserveKeyOrder()},
+			want:    `{"msg":"served","http":{"status":200,"method":"GET","url":{"path":"/"}}}`,
+		},
+		{
+			options: []log.JSONOption{log.JSONNested("/")},
+			want:    `{"http.method":"GET","http.status":200,"http.url.path":"/","msg":"served"}`,
+		},
+	}
+
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		logger := log.NewJSONLoggerWithOptions(buf, test.options...)
+		if err := logger.Log(keyvals...); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := test.want+"\n", buf.String(); want != have {
+			t.Errorf("\nwant %s\nhave %s", want, have)
+		}
+	}
+}
+
+func TestJSONLoggerNestedConflict(t *testing.T) {
+	t.Parallel()
+	// The result does not depend on whether the leaf or the object comes
+	// first.
+	for _, keyvals := range [][]interface{}{
+		{"http", "leaf", "http.method", "GET"},
+		{"http.method", "GET", "http", "leaf"},
+	} {
+		buf := &bytes.Buffer{}
+		logger := log.NewJSONLoggerWithOptions(buf, log.JSONNested("."))
+		if err := logger.Log(keyvals...); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := `{"http":{"_value":"leaf","method":"GET"}}`+"\n", buf.String(); want != have {
+			t.Errorf("%v:\nwant %s\nhave %s", keyvals, want, have)
+		}
+	}
+}
+
+func TestJSONLoggerNestedEmptySegment(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewJSONLoggerWithOptions(buf, log.JSONNested("."), log.JSONPreserveKeyOrder())
+	if err := logger.Log("a..b", 1, ".x", 2, "y.", 3, "a.c", 4, ".", 5); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"a..b":1,".x":2,"y.":3,"a":{"c":4},".":5}`+"\n", buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestJSONLoggerNestedDuplicates(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewJSONLoggerWithOptions(buf, log.JSONNested("."), log.JSONPreserveKeyOrder())
+	if err := logger.Log("a.b", 1, "a.c", 2, "a.b", 3); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"a":{"b":3,"c":2}}`+"\n", buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+
+	buf.Reset()
+	logger = log.NewJSONLoggerWithOptions(buf, log.JSONNested("."), log.JSONPreserveKeyOrder(), log.JSONDuplicateKeys(log.DuplicateKeysKeepAll))
+	if err := logger.Log("a.b", 1, "a.c", 2, "a.b", 3); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"a":{"b":1,"c":2,"b":3}}`+"\n", buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestJSONLoggerNestedEncodeError(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewJSONLoggerWithOptions(buf, log.JSONNested("."), log.JSONEncodeErrorKey("_encode_error"))
+	if err := logger.Log("a.b", math.NaN()); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"a":{"b":"ENCODE ERROR: json: unsupported value: NaN"},"_encode_error":"a.b: json: unsupported value: NaN"}` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func BenchmarkNestedJSONLoggerSimple(b *testing.B) {
+	benchmarkRunner(b, log.NewJSONLoggerWithOptions(ioutil.Discard, log.JSONNested(".")), baseMessage)
+}
@@ -2,7 +2,10 @@ package log
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"reflect"
 	"sync"
 
 	"github.com/go-logfmt/logfmt"
@@ -27,15 +30,67 @@ var logfmtEncoderPool = sync.Pool{
 }
 
 type logfmtLogger struct {
-	w io.Writer
+	w            io.Writer
+	errorHandler func(error)
+	flatten      *flattener
 }
 
 // NewLogfmtLogger returns a logger that encodes keyvals to the Writer in
 // logfmt format. Each log event produces no more than one call to w.Write.
 // The passed Writer must be safe for concurrent use by multiple goroutines if
 // the returned Logger will be used concurrently.
+//
+// A key/value pair that cannot be encoded does not prevent the rest of the
+// log event from being written. Values of unsupported types, such as maps
+// and structs, are replaced with a placeholder like <unsupported map>, as are
+// values whose MarshalText method fails. Keys of unsupported types are
+// replaced with their fmt.Sprint formatting, as are keys whose MarshalText
+// method fails. Pairs with a nil key or a key left empty once invalid
+// characters are removed are skipped, as are pairs that cannot be encoded
+// even after these replacements.
 func NewLogfmtLogger(w io.Writer) Logger {
-	return &logfmtLogger{w}
+	return &logfmtLogger{w: w}
+}
+
+// NewLogfmtLoggerWithOptions returns a Logger like the one returned by
+// NewLogfmtLogger, configured by options.
+func NewLogfmtLoggerWithOptions(w io.Writer, options ...LogfmtOption) Logger {
+	l := &logfmtLogger{w: w}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// LogfmtOption sets a parameter for the Logger returned by
+// NewLogfmtLoggerWithOptions.
+type LogfmtOption func(*logfmtLogger)
+
+// LogfmtErrorHandler sets a function that is called with an error for each
+// key/value pair that had to be replaced or skipped. By default those errors
+// are discarded.
+func LogfmtErrorHandler(f func(error)) LogfmtOption {
+	return func(l *logfmtLogger) { l.errorHandler = f }
+}
+
+// LogfmtFlatten expands maps, structs, slices and arrays into one key/value
+// pair per leaf value, since logfmt cannot represent nested data. The keys
+// along the path to a leaf are joined with separator, so that
+//
+//	logger.Log("req", map[string]interface{}{"header": map[string]string{"host": "example.com"}}, "ids", []int{7, 9})
+//
+// produces
+//
+//	req.header.host=example.com ids.0=7 ids.1=9
+//
+// when separator is ".". Map entries are written in key order and struct
+// fields are named by their json tag if they have one. Values that implement
+// error, fmt.Stringer, or encoding.TextMarshaler are not expanded, nor are
+// values nested more than maxDepth levels deep; a maxDepth less than one
+// means 10. The fields match those written by a JSON Logger configured with
+// JSONFlatten.
+func LogfmtFlatten(maxDepth int, separator string) LogfmtOption {
+	return func(l *logfmtLogger) { l.flatten = newFlattener(maxDepth, separator) }
 }
 
 func (l logfmtLogger) Log(keyvals ...interface{}) error {
@@ -43,8 +98,17 @@ func (l logfmtLogger) Log(keyvals ...interface{}) error {
 	enc.Reset()
 	defer logfmtEncoderPool.Put(enc)
 
-	if err := enc.EncodeKeyvals(keyvals...); err != nil {
-		return err
+	if l.flatten != nil {
+		keyvals = l.flatten.keyvals(keyvals)
+	}
+
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{}
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		l.encodeKeyval(enc, k, v)
 	}
 
 	// Add newline to the end of the buffer
@@ -60,3 +124,45 @@ func (l logfmtLogger) Log(keyvals ...interface{}) error {
 	}
 	return nil
 }
+
+// encodeKeyval encodes one key/value pair. A key that cannot be encoded is
+// replaced with its fmt.Sprint formatting and a value that cannot be encoded
+// with a placeholder. The pair is skipped if its key is nil or empty, or if
+// it cannot be encoded even after the replacements.
+func (l logfmtLogger) encodeKeyval(enc *logfmtEncoder, k, v interface{}) {
+	err := enc.EncodeKeyval(k, v)
+	if err == nil {
+		return
+	}
+	if l.errorHandler != nil {
+		l.errorHandler(fmt.Errorf("logfmt: key %v: %w", k, err))
+	}
+	if err == logfmt.ErrNilKey || err == logfmt.ErrInvalidKey {
+		return
+	}
+	// The error does not tell whether the key or the value failed, so the
+	// key is checked on its own.
+	if logfmt.NewEncoder(ioutil.Discard).EncodeKeyval(k, nil) != nil {
+		k = fmt.Sprint(k)
+		if err = enc.EncodeKeyval(k, v); err == nil {
+			return
+		}
+	}
+	if err == logfmt.ErrUnsupportedValueType {
+		v = unsupportedValue(v)
+	} else {
+		v = err.Error()
+	}
+	// The pair is skipped if this fails too.
+	_ = enc.EncodeKeyval(k, v)
+}
+
+// unsupportedValue returns a placeholder for a value of a type logfmt cannot
+// encode.
+func unsupportedValue(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return "<unsupported " + rv.Kind().String() + ">"
+}
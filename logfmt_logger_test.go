@@ -34,7 +34,7 @@ func TestLogfmtLogger(t *testing.T) {
 	if err := logger.Log("std_map", map[int]int{1: 2}, "my_map", mymap{0: 0}); err != nil {
 		t.Fatal(err)
 	}
-	if want, have := "std_map=\""+logfmt.ErrUnsupportedValueType.Error()+"\" my_map=special_behavior\n", buf.String(); want != have {
+	if want, have := "std_map=\"<unsupported map>\" my_map=special_behavior\n", buf.String(); want != have {
 		t.Errorf("want %#v, have %#v", want, have)
 	}
 }
@@ -47,6 +47,10 @@ func BenchmarkLogfmtLoggerContextual(b *testing.B) {
 	benchmarkRunner(b, log.NewLogfmtLogger(ioutil.Discard), withMessage)
 }
 
+func BenchmarkLogfmtLoggerTyped(b *testing.B) {
+	benchmarkRunner(b, log.NewLogfmtLogger(ioutil.Discard), typedMessage)
+}
+
 func TestLogfmtLoggerConcurrency(t *testing.T) {
 	t.Parallel()
 	testConcurrency(t, log.NewLogfmtLogger(ioutil.Discard), 10000)
@@ -55,3 +59,74 @@ func TestLogfmtLoggerConcurrency(t *testing.T) {
 type mymap map[int]int
 
 func (m mymap) String() string { return "special_behavior" }
+
+func TestLogfmtLoggerRecovery(t *testing.T) {
+	t.Parallel()
+	var errs []error
+	buf := &bytes.Buffer{}
+	logger := log.NewLogfmtLoggerWithOptions(buf, log.LogfmtErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+
+	err := logger.Log(
+		"a", 1,
+		nil, "nil key",
+		"ptr", &struct{}{},
+		" = ", "invalid key",
+		[]int{1}, "slice key",
+		"text", failingMarshaler{},
+		"z", 26,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `a=1 ptr="<unsupported struct>" [1]="slice key" text="error marshaling value of type log_test.failingMarshaler: boom" z=26` + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+
+	wantErrs := []error{logfmt.ErrNilKey, logfmt.ErrUnsupportedValueType, logfmt.ErrInvalidKey, logfmt.ErrUnsupportedKeyType, nil}
+	if want, have := len(wantErrs), len(errs); want != have {
+		t.Fatalf("want %d errors, have %d: %v", want, have, errs)
+	}
+	for i, want := range wantErrs {
+		if want == nil {
+			var merr *logfmt.MarshalerError
+			if !errors.As(errs[i], &merr) {
+				t.Errorf("errs[%d]: want *logfmt.MarshalerError, have %v", i, errs[i])
+			}
+			continue
+		}
+		if !errors.Is(errs[i], want) {
+			t.Errorf("errs[%d]: want %v, have %v", i, want, errs[i])
+		}
+	}
+}
+
+func TestLogfmtLoggerRecoveryKeyAndValue(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		keyvals []interface{}
+		want    string
+	}{
+		{[]interface{}{struct{ A int }{1}, map[string]int{}, "z", 1}, `{1}="<unsupported map>" z=1`},
+		{[]interface{}{[]int{1}, []int{2}, "z", 1}, `[1]="<unsupported slice>" z=1`},
+		{[]interface{}{failingMarshaler{}, "v", "z", 1}, `{}=v z=1`},
+		{[]interface{}{failingMarshaler{}, failingMarshaler{}, "z", 1}, `{}="error marshaling value of type log_test.failingMarshaler: boom" z=1`},
+	} {
+		buf := &bytes.Buffer{}
+		if err := log.NewLogfmtLogger(buf).Log(test.keyvals...); err != nil {
+			t.Errorf("%v: %v", test.keyvals, err)
+			continue
+		}
+		if want, have := test.want+"\n", buf.String(); want != have {
+			t.Errorf("%v:\nwant %#v\nhave %#v", test.keyvals, want, have)
+		}
+	}
+}
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalText() ([]byte, error) {
+	return nil, errors.New("boom")
+}
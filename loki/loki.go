@@ -0,0 +1,340 @@
+// Package loki provides a Logger that ships log events to Grafana Loki
+// through its push API.
+//
+// Log never blocks on the network: log events are queued and pushed in
+// batches from a background goroutine, which retries failed pushes with
+// exponential backoff. Call Close before the program exits to push the
+// queued log events.
+//
+//	client := loki.NewClient("http://localhost:3100/loki/api/v1/push",
+//		loki.Labels("level", "app"),
+//		loki.StaticLabels("job", "checkout"),
+//	)
+//	defer client.Close()
+//	logger := log.With(client, "ts", log.DefaultTimestampUTC)
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/batch"
+	"github.com/go-kit/log/internal/fields"
+)
+
+// Errors returned by Log.
+var (
+	ErrQueueFull = batch.ErrQueueFull // the queue is full
+	ErrClosed    = batch.ErrClosed    // Close has been called
+)
+
+// Client is a Logger that pushes log events to Loki. See NewClient for
+// details.
+type Client struct {
+	url     string
+	client  *http.Client
+	header  http.Header
+	labels  map[string]bool
+	static  map[string]string
+	cfg     batch.Config
+	batcher *batch.Batcher
+	bufPool sync.Pool
+}
+
+// Option sets a parameter for a Client.
+type Option func(*Client)
+
+// Labels sets the keys whose values become stream labels rather than part of
+// the log line. Loki indexes log streams by their labels, so only keys with
+// few distinct values, such as the level or the application name, should be
+// labels. The default is "level".
+func Labels(keys ...string) Option {
+	return func(c *Client) {
+		c.labels = map[string]bool{}
+		for _, k := range keys {
+			c.labels[k] = true
+		}
+	}
+}
+
+// StaticLabels adds labels, given as alternating names and values, to every
+// stream.
+func StaticLabels(namevals ...string) Option {
+	return func(c *Client) {
+		for i := 0; i+1 < len(namevals); i += 2 {
+			c.static[labelName(namevals[i])] = namevals[i+1]
+		}
+	}
+}
+
+// TenantID sets the tenant that log events are pushed for, in a Loki with
+// multi-tenancy enabled.
+func TenantID(id string) Option {
+	return Header("X-Scope-OrgID", id)
+}
+
+// HTTPClient sets the client used to send push requests. The default is a
+// client with a 10 second timeout.
+func HTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.client = hc }
+}
+
+// Header adds a header, such as one holding credentials, to every push
+// request.
+func Header(key, value string) Option {
+	return func(c *Client) { c.header.Add(key, value) }
+}
+
+// BatchSize sets the maximum number of log events in a push request, 500 by
+// default.
+func BatchSize(n int) Option {
+	return batchOption(batch.MaxSize(n))
+}
+
+// FlushInterval sets how often a partial batch is pushed, one second by
+// default.
+func FlushInterval(d time.Duration) Option {
+	return batchOption(batch.Interval(d))
+}
+
+// QueueSize sets the maximum number of queued log events, 10000 by default.
+func QueueSize(n int) Option {
+	return batchOption(batch.QueueSize(n))
+}
+
+// Retry sets how failed pushes are retried. By default they are retried 5
+// times, after delays from 100ms to 10s.
+func Retry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return batchOption(batch.Retry(maxRetries, minBackoff, maxBackoff))
+}
+
+// ErrorHandler sets the function that receives push errors, which are
+// discarded by default.
+func ErrorHandler(f func(error)) Option {
+	return batchOption(batch.ErrorHandler(f))
+}
+
+// batchOption returns an Option that applies o to the batch.Config of the
+// Client.
+func batchOption(o batch.Option) Option {
+	return func(c *Client) { o(&c.cfg) }
+}
+
+// NewClient returns a Client that pushes log events to url, the full URL of
+// the Loki push API, usually ending in /loki/api/v1/push. It starts a
+// background goroutine, which Close stops.
+//
+// The values of the label keys set by Labels become the labels of the log
+// event's stream, along with the static labels. Label names are the keys
+// with characters Loki does not allow replaced by underscores. A time.Time or
+// timestamp Valuer value under "ts" becomes the timestamp of the entry,
+// which is otherwise the time of the Log call. The remaining key/value pairs
+// are encoded as a logfmt line, in the order they were logged.
+func NewClient(url string, options ...Option) *Client {
+	c := &Client{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		header: http.Header{},
+		labels: map[string]bool{"level": true},
+		static: map[string]string{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	c.batcher = batch.New(c.push, c.cfg)
+	return c
+}
+
+// entry is a queued log event.
+type entry struct {
+	selector string // identifies the stream
+	labels   map[string]string
+	ts       time.Time
+	line     string
+}
+
+type lineBuf struct {
+	buf    bytes.Buffer
+	logger log.Logger
+	kvs    []interface{}
+}
+
+// Log encodes keyvals as a log line and queues it to be pushed. It does not
+// block, and returns ErrQueueFull if the queue is full.
+func (c *Client) Log(keyvals ...interface{}) error {
+	lb, _ := c.bufPool.Get().(*lineBuf)
+	if lb == nil {
+		lb = &lineBuf{}
+		lb.logger = log.NewLogfmtLogger(&lb.buf)
+	}
+	defer func() {
+		for i := range lb.kvs {
+			lb.kvs[i] = nil
+		}
+		lb.kvs = lb.kvs[:0]
+		lb.buf.Reset()
+		c.bufPool.Put(lb)
+	}()
+
+	var ts time.Time
+	labels := make(map[string]string, len(c.labels)+len(c.static))
+	for name, v := range c.static {
+		labels[name] = v
+	}
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		if k == "ts" {
+			if t, ok := fields.Time(v); ok {
+				ts = t
+				continue
+			}
+		}
+		if name, ok := k.(string); ok && c.labels[name] {
+			labels[labelName(name)] = fmt.Sprint(v)
+			continue
+		}
+		lb.kvs = append(lb.kvs, k, v)
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	if err := lb.logger.Log(lb.kvs...); err != nil {
+		return err
+	}
+	return c.batcher.Add(entry{
+		selector: selector(labels),
+		labels:   labels,
+		ts:       ts,
+		line:     strings.TrimSuffix(lb.buf.String(), "\n"),
+	})
+}
+
+// selector returns the stream selector for labels, such as
+// {app="x",level="info"}, which uniquely identifies the stream.
+func selector(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Dropped returns the number of log events Log dropped with ErrQueueFull.
+func (c *Client) Dropped() uint64 {
+	return c.batcher.Dropped()
+}
+
+// Flush blocks until the log events passed to Log before it have been pushed
+// or given up on. It returns ctx.Err() if ctx is done first.
+func (c *Client) Flush(ctx context.Context) error {
+	return c.batcher.Flush(ctx)
+}
+
+// Close stops accepting log events and blocks until the queued log events
+// have been pushed or given up on.
+func (c *Client) Close() error {
+	return c.batcher.Shutdown(context.Background())
+}
+
+type pushRequest struct {
+	Streams []stream `json:"streams"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// push sends one batch of log events, grouped into streams.
+func (c *Client) push(ctx context.Context, items []interface{}) error {
+	var (
+		req     pushRequest
+		entries = map[string][]entry{}
+		order   []string
+	)
+	for _, item := range items {
+		e := item.(entry)
+		if _, ok := entries[e.selector]; !ok {
+			order = append(order, e.selector)
+		}
+		entries[e.selector] = append(entries[e.selector], e)
+	}
+	for _, selector := range order {
+		es := entries[selector]
+		// Loki rejects entries that are older than the newest entry of
+		// their stream it has received, so send each stream in order.
+		sort.SliceStable(es, func(i, j int) bool { return es[i].ts.Before(es[j].ts) })
+		s := stream{Stream: es[0].labels, Values: make([][2]string, len(es))}
+		for i, e := range es {
+			s.Values[i] = [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line}
+		}
+		req.Streams = append(req.Streams, s)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return batch.Permanent(fmt.Errorf("loki: %w", err))
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return batch.Permanent(fmt.Errorf("loki: %w", err))
+	}
+	for k, v := range c.header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("loki: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("loki: push failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return batch.Permanent(fmt.Errorf("loki: push failed: %s: %s", resp.Status, bytes.TrimSpace(msg)))
+}
+
+// labelName returns k with the characters Loki does not allow in label names
+// replaced by underscores.
+func labelName(k string) string {
+	b := []byte(k)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,162 @@
+package loki_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-kit/log/loki"
+)
+
+type pushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+// server is a stand-in for the Loki push API that records the requests it
+// receives and responds with the status codes in statuses, in order, before
+// responding with 204.
+type server struct {
+	mu       sync.Mutex
+	requests []pushRequest
+	headers  []http.Header
+	statuses []int
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.statuses) > 0 {
+		w.WriteHeader(s.statuses[0])
+		s.statuses = s.statuses[1:]
+		return
+	}
+	var req pushRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, req)
+	s.headers = append(s.headers, r.Header)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	s := &server{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	client := loki.NewClient(srv.URL+"/loki/api/v1/push",
+		loki.Labels("level", "app"),
+		loki.StaticLabels("job", "test"),
+		loki.TenantID("tenant-1"),
+		loki.FlushInterval(time.Hour),
+	)
+	t0 := time.Unix(1700000000, 0)
+	ts := func(d time.Duration) log.Valuer {
+		return log.TimestampFormat(func() time.Time { return t0.Add(d) }, time.RFC3339)
+	}
+	logger := log.With(client, "app", "web")
+	level.Info(log.With(logger, "ts", ts(2))).Log("msg", "second")
+	level.Info(log.With(logger, "ts", ts(1))).Log("msg", "first", "path", "/a b")
+	level.Error(log.With(logger, "ts", ts(3))).Log("msg", "failed", "err", "timeout")
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) != 1 {
+		t.Fatalf("want 1 request, have %d", len(s.requests))
+	}
+	if want, have := "tenant-1", s.headers[0].Get("X-Scope-OrgID"); want != have {
+		t.Errorf("X-Scope-OrgID: want %q, have %q", want, have)
+	}
+	have := s.requests[0]
+	var want pushRequest
+	json.Unmarshal([]byte(`{"streams":[
+		{"stream":{"app":"web","job":"test","level":"info"},"values":[
+			["1700000000000000001","msg=first path=\"/a b\""],
+			["1700000000000000002","msg=second"]]},
+		{"stream":{"app":"web","job":"test","level":"error"},"values":[
+			["1700000000000000003","msg=failed err=timeout"]]}]}`), &want)
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %+v\nhave %+v", want, have)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	t.Parallel()
+	s := &server{statuses: []int{http.StatusTooManyRequests, http.StatusInternalServerError}}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	client := loki.NewClient(srv.URL, loki.FlushInterval(time.Hour), loki.Retry(5, time.Millisecond, time.Millisecond))
+	client.Log("msg", "x")
+	client.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) != 1 || len(s.requests[0].Streams) != 1 {
+		t.Fatalf("want 1 request with 1 stream, have %+v", s.requests)
+	}
+	stream := s.requests[0].Streams[0]
+	if want, have := map[string]string{}, stream.Stream; !reflect.DeepEqual(want, have) {
+		t.Errorf("want labels %v, have %v", want, have)
+	}
+	if want, have := "msg=x", stream.Values[0][1]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestClientBadRequest(t *testing.T) {
+	t.Parallel()
+	s := &server{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	var errs []error
+	client := loki.NewClient(srv.URL,
+		loki.FlushInterval(time.Hour),
+		loki.Retry(5, time.Millisecond, time.Millisecond),
+		loki.ErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	client.Log("msg", "x")
+	client.Close()
+	if len(errs) != 1 {
+		t.Errorf("want 1 error, have %v", errs)
+	}
+	if want, have := loki.ErrClosed, client.Log("msg", "late"); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestClientBatchSize(t *testing.T) {
+	t.Parallel()
+	s := &server{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	client := loki.NewClient(srv.URL, loki.BatchSize(2), loki.FlushInterval(time.Hour))
+	for i := 0; i < 5; i++ {
+		client.Log("i", i)
+	}
+	client.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if want, have := 3, len(s.requests); want != have {
+		t.Errorf("want %d requests, have %d", want, have)
+	}
+}
@@ -0,0 +1,262 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// MsgpackDecoder reads log events written by a MessagePack logger. It is
+// intended for tooling and tests rather than high-volume processing.
+type MsgpackDecoder struct {
+	r *bufio.Reader
+}
+
+// MsgpackExt is a MessagePack extension value of a type the MsgpackDecoder
+// does not interpret.
+type MsgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+// NewMsgpackDecoder returns a MsgpackDecoder that reads from r.
+func NewMsgpackDecoder(r io.Reader) *MsgpackDecoder {
+	return &MsgpackDecoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next log event and returns its key/value pairs in the
+// order they were encoded. It returns io.EOF when there are no more events,
+// and io.ErrUnexpectedEOF if the input ends part way through one.
+//
+// Strings decode as string, binary as []byte, non-negative integers as
+// uint64 and negative integers as int64, floats as float64, and timestamp
+// extension values as time.Time. Arrays decode as []interface{}, and maps as
+// map[string]interface{} when all their keys are strings or
+// map[interface{}]interface{} otherwise. Values of other extension types
+// decode as MsgpackExt.
+func (d *MsgpackDecoder) Decode() ([]interface{}, error) {
+	if _, err := d.r.Peek(1); err != nil {
+		return nil, err
+	}
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var n uint64
+	switch {
+	case c&0xf0 == msgpackFixMap:
+		n = uint64(c & 0x0f)
+	case c == msgpackMap16, c == msgpackMap32:
+		if n, err = d.readUint(2 << (c - msgpackMap16)); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	default:
+		return nil, fmt.Errorf("msgpack: log event starts with %#x, not a map", c)
+	}
+	keyvals := make([]interface{}, 0, 2*min64(n, maxBinaryPrealloc))
+	for i := uint64(0); i < n; i++ {
+		k, err := d.readItem(0)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		v, err := d.readItem(0)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		keyvals = append(keyvals, k, v)
+	}
+	return keyvals, nil
+}
+
+// DecodeValue reads the next value, which need not be a map, and returns it
+// decoded as described for Decode. It is useful for reading protocols that
+// embed log events in other MessagePack structures.
+func (d *MsgpackDecoder) DecodeValue() (interface{}, error) {
+	if _, err := d.r.Peek(1); err != nil {
+		return nil, err
+	}
+	v, err := d.readItem(0)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return v, nil
+}
+
+// readUint reads a big-endian unsigned integer of size bytes.
+func (d *MsgpackDecoder) readUint(size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[:size]); err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, b := range buf[:size] {
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
+
+func (d *MsgpackDecoder) readBytes(n uint64) ([]byte, error) {
+	b := make([]byte, 0, min64(n, maxBinaryPrealloc))
+	for n > 0 {
+		chunk := min64(n, maxBinaryPrealloc)
+		start := len(b)
+		b = append(b, make([]byte, chunk)...)
+		if _, err := io.ReadFull(d.r, b[start:]); err != nil {
+			return nil, err
+		}
+		n -= chunk
+	}
+	return b, nil
+}
+
+func (d *MsgpackDecoder) readItem(depth int) (interface{}, error) {
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("msgpack: %w", errMaxDepth)
+	}
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c <= math.MaxInt8:
+		return uint64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == msgpackFixMap:
+		return d.readMap(uint64(c&0x0f), depth)
+	case c&0xf0 == msgpackFixArray:
+		return d.readArray(uint64(c&0x0f), depth)
+	case c&0xe0 == msgpackFixStr:
+		b, err := d.readBytes(uint64(c & 0x1f))
+		return string(b), err
+	}
+
+	switch c {
+	case msgpackNil:
+		return nil, nil
+	case msgpackFalse:
+		return false, nil
+	case msgpackTrue:
+		return true, nil
+	case msgpackUint8, msgpackUint16, msgpackUint32, msgpackUint64:
+		return d.readUint(1 << (c - msgpackUint8))
+	case msgpackInt8, msgpackInt16, msgpackInt32, msgpackInt64:
+		size := 1 << (c - msgpackInt8)
+		n, err := d.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from size bytes.
+		shift := uint(64 - 8*size)
+		if v := int64(n<<shift) >> shift; v < 0 {
+			return v, nil
+		}
+		return n, nil
+	case msgpackFloat32:
+		n, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case msgpackFloat64:
+		n, err := d.readUint(8)
+		return math.Float64frombits(n), err
+	case msgpackStr8, msgpackStr16, msgpackStr32:
+		n, err := d.readUint(1 << (c - msgpackStr8))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.readBytes(n)
+		return string(b), err
+	case msgpackBin8, msgpackBin16, msgpackBin32:
+		n, err := d.readUint(1 << (c - msgpackBin8))
+		if err != nil {
+			return nil, err
+		}
+		return d.readBytes(n)
+	case msgpackArray16, msgpackArray32:
+		n, err := d.readUint(2 << (c - msgpackArray16))
+		if err != nil {
+			return nil, err
+		}
+		return d.readArray(n, depth)
+	case msgpackMap16, msgpackMap32:
+		n, err := d.readUint(2 << (c - msgpackMap16))
+		if err != nil {
+			return nil, err
+		}
+		return d.readMap(n, depth)
+	case msgpackFixExt1, msgpackFixExt2, msgpackFixExt4, msgpackFixExt8, msgpackFixExt16:
+		return d.readExt(1 << (c - msgpackFixExt1))
+	case msgpackExt8, msgpackExt16, msgpackExt32:
+		n, err := d.readUint(1 << (c - msgpackExt8))
+		if err != nil {
+			return nil, err
+		}
+		return d.readExt(n)
+	}
+	return nil, fmt.Errorf("msgpack: invalid format byte %#x", c)
+}
+
+func (d *MsgpackDecoder) readArray(n uint64, depth int) (interface{}, error) {
+	a := make([]interface{}, 0, min64(n, maxBinaryPrealloc))
+	for i := uint64(0); i < n; i++ {
+		v, err := d.readItem(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+func (d *MsgpackDecoder) readMap(n uint64, depth int) (interface{}, error) {
+	keys := make([]interface{}, 0, min64(n, maxBinaryPrealloc))
+	values := make([]interface{}, 0, min64(n, maxBinaryPrealloc))
+	for i := uint64(0); i < n; i++ {
+		k, err := d.readItem(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.readItem(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		keys, values = append(keys, k), append(values, v)
+	}
+	m, err := decodedMap(keys, values)
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: %w", err)
+	}
+	return m, nil
+}
+
+// readExt reads the type and n bytes of data of an extension value.
+func (d *MsgpackDecoder) readExt(n uint64) (interface{}, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.readBytes(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ) != msgpackTimestampType {
+		return MsgpackExt{Type: int8(typ), Data: data}, nil
+	}
+	be := func(b []byte) (n uint64) {
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n
+	}
+	switch len(data) {
+	case 4:
+		return time.Unix(int64(be(data)), 0), nil
+	case 8:
+		v := be(data)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)), nil
+	case 12:
+		return time.Unix(int64(be(data[4:])), int64(be(data[:4]))), nil
+	}
+	return nil, fmt.Errorf("msgpack: invalid timestamp length %d", len(data))
+}
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"io"
+	"math"
+	"time"
+)
+
+// MessagePack format bytes.
+const (
+	msgpackNil      = 0xc0
+	msgpackFalse    = 0xc2
+	msgpackTrue     = 0xc3
+	msgpackBin8     = 0xc4
+	msgpackBin16    = 0xc5
+	msgpackBin32    = 0xc6
+	msgpackExt8     = 0xc7
+	msgpackExt16    = 0xc8
+	msgpackExt32    = 0xc9
+	msgpackFloat32  = 0xca
+	msgpackFloat64  = 0xcb
+	msgpackUint8    = 0xcc
+	msgpackUint16   = 0xcd
+	msgpackUint32   = 0xce
+	msgpackUint64   = 0xcf
+	msgpackInt8     = 0xd0
+	msgpackInt16    = 0xd1
+	msgpackInt32    = 0xd2
+	msgpackInt64    = 0xd3
+	msgpackFixExt1  = 0xd4
+	msgpackFixExt2  = 0xd5
+	msgpackFixExt4  = 0xd6
+	msgpackFixExt8  = 0xd7
+	msgpackFixExt16 = 0xd8
+	msgpackStr8     = 0xd9
+	msgpackStr16    = 0xda
+	msgpackStr32    = 0xdb
+	msgpackArray16  = 0xdc
+	msgpackArray32  = 0xdd
+	msgpackMap16    = 0xde
+	msgpackMap32    = 0xdf
+
+	msgpackFixMap   = 0x80
+	msgpackFixArray = 0x90
+	msgpackFixStr   = 0xa0
+
+	msgpackTimestampType = -1
+)
+
+// NewMsgpackLogger returns a Logger that encodes each log event to the
+// Writer as a MessagePack map. Each log event produces no more than one call
+// to w.Write, and log events can be written back to back to a stream and
+// read with a MsgpackDecoder. The passed Writer must be safe for concurrent
+// use by multiple goroutines if the returned Logger will be used
+// concurrently.
+//
+// Keys are encoded as strings. Integers, floats, booleans, nil, strings and
+// byte slices are encoded as the native MessagePack types, using the
+// smallest format that holds the value, and time.Time values, including
+// those from TimestampFormat Valuers, with the timestamp extension type.
+// Errors and fmt.Stringers are encoded as strings from their Error and String
+// methods, and encoding.TextMarshalers as their text. Slices, arrays, maps
+// and structs become MessagePack arrays and maps. Values that have no
+// MessagePack representation, such as channels, are replaced with a
+// placeholder like <unsupported chan>.
+func NewMsgpackLogger(w io.Writer) Logger {
+	return &binaryLogger{w: w, format: msgpackFormat{}}
+}
+
+// msgpackFormat implements binaryFormat for MessagePack.
+type msgpackFormat struct{}
+
+func (msgpackFormat) appendNil(b []byte) []byte { return append(b, msgpackNil) }
+
+func (msgpackFormat) appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, msgpackTrue)
+	}
+	return append(b, msgpackFalse)
+}
+
+func (f msgpackFormat) appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return f.appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v)) // negative fixint
+	case v >= math.MinInt8:
+		return append(b, msgpackInt8, byte(v))
+	case v >= math.MinInt16:
+		return append(b, msgpackInt16, byte(v>>8), byte(v))
+	case v >= math.MinInt32:
+		return append(b, msgpackInt32, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return appendUint64(append(b, msgpackInt64), uint64(v))
+	}
+}
+
+func (msgpackFormat) appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= math.MaxInt8:
+		return append(b, byte(v)) // positive fixint
+	case v <= math.MaxUint8:
+		return append(b, msgpackUint8, byte(v))
+	case v <= math.MaxUint16:
+		return append(b, msgpackUint16, byte(v>>8), byte(v))
+	case v <= math.MaxUint32:
+		return append(b, msgpackUint32, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return appendUint64(append(b, msgpackUint64), v)
+	}
+}
+
+func (msgpackFormat) appendFloat32(b []byte, v float32) []byte {
+	n := math.Float32bits(v)
+	return append(b, msgpackFloat32, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (msgpackFormat) appendFloat64(b []byte, v float64) []byte {
+	return appendUint64(append(b, msgpackFloat64), math.Float64bits(v))
+}
+
+func (msgpackFormat) appendString(b []byte, v string) []byte {
+	n := len(v)
+	switch {
+	case n < 32:
+		b = append(b, msgpackFixStr|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, msgpackStr8, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, msgpackStr16, byte(n>>8), byte(n))
+	default:
+		b = append(b, msgpackStr32, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, v...)
+}
+
+func (msgpackFormat) appendBytes(b []byte, v []byte) []byte {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, msgpackBin8, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, msgpackBin16, byte(n>>8), byte(n))
+	default:
+		b = append(b, msgpackBin32, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, v...)
+}
+
+// appendTime appends t with the timestamp extension type, in the smallest
+// of its three formats that holds t.
+func (msgpackFormat) appendTime(b []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	switch {
+	case sec >= 0 && sec <= math.MaxUint32 && nsec == 0:
+		return append(b, msgpackFixExt4, 0xff, byte(sec>>24), byte(sec>>16), byte(sec>>8), byte(sec))
+	case sec >= 0 && sec < 1<<34:
+		return appendUint64(append(b, msgpackFixExt8, 0xff), nsec<<34|uint64(sec))
+	default:
+		b = append(b, msgpackExt8, 12, 0xff, byte(nsec>>24), byte(nsec>>16), byte(nsec>>8), byte(nsec))
+		return appendUint64(b, uint64(sec))
+	}
+}
+
+func (msgpackFormat) appendArrayHeader(b []byte, n int) []byte {
+	return appendMsgpackHeader(b, msgpackFixArray, msgpackArray16, msgpackArray32, n)
+}
+
+func (msgpackFormat) appendMapHeader(b []byte, n int) []byte {
+	return appendMsgpackHeader(b, msgpackFixMap, msgpackMap16, msgpackMap32, n)
+}
+
+func appendMsgpackHeader(b []byte, fix, c16, c32 byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, c16, byte(n>>8), byte(n))
+	default:
+		return append(b, c32, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
@@ -0,0 +1,194 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestMsgpackLoggerEncoding(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		value interface{}
+		want  string
+	}{
+		{0, "00"},
+		{127, "7f"},
+		{128, "cc80"},
+		{uint16(1000), "cd03e8"},
+		{70000, "ce00011170"},
+		{int64(1) << 40, "cf0000010000000000"},
+		{-1, "ff"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{-1000, "d1fc18"},
+		{-70000, "d2fffeee90"},
+		{int64(-1) << 40, "d3ffffff0000000000"},
+		{1.5, "cb3ff8000000000000"},
+		{float32(1.5), "ca3fc00000"},
+		{false, "c2"},
+		{true, "c3"},
+		{nil, "c0"},
+		{"abc", "a3616263"},
+		{strings.Repeat("x", 32), "d920" + strings.Repeat("78", 32)},
+		{[]byte{1, 2}, "c4020102"},
+		{[]string{"a"}, "91a161"},
+		{map[string]int{"b": 2, "a": 1}, "82a16101a16202"},
+		{time.Unix(1, 0), "d6ff00000001"},
+		{time.Unix(1, 1), "d7ff0000000400000001"},
+		{time.Unix(-1, 0), "c70cff00000000ffffffffffffffff"},
+		{errors.New("abc"), "a3616263"},
+		{make(chan int), "b23c756e737570706f72746564206368616e3e"},
+	} {
+		buf := &bytes.Buffer{}
+		if err := log.NewMsgpackLogger(buf).Log("k", test.value); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "81a16b"+test.want, hex.EncodeToString(buf.Bytes()); want != have {
+			t.Errorf("%#v:\nwant %s\nhave %s", test.value, want, have)
+		}
+	}
+}
+
+func TestMsgpackLoggerRoundTrip(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewMsgpackLogger(buf)
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	far := time.Date(2600, 1, 1, 0, 0, 0, 5, time.UTC)
+	if err := logger.Log(
+		"ts", log.TimestampFormat(func() time.Time { return ts }, time.RFC3339)(),
+		"far", far,
+		"msg", "hello",
+		"n", -42,
+		"big", uint64(1)<<63,
+		"err", errors.New("boom"),
+		"nested", map[string]interface{}{"a": []interface{}{1, "x"}},
+		"ints", map[int]bool{1: true},
+		"odd",
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Log("second", 1.25); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := log.NewMsgpackDecoder(buf)
+	have, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		"ts", ts,
+		"far", far,
+		"msg", "hello",
+		"n", int64(-42),
+		"big", uint64(1) << 63,
+		"err", "boom",
+		"nested", map[string]interface{}{"a": []interface{}{uint64(1), "x"}},
+		"ints", map[interface{}]interface{}{uint64(1): true},
+		"odd", log.ErrMissingValue.Error(),
+	}
+	for _, i := range []int{1, 3} {
+		// time.Time values must be compared with Equal.
+		if ht, ok := have[i].(time.Time); ok && ht.Equal(want[i].(time.Time)) {
+			have[i] = want[i]
+		}
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+
+	have, err = dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"second", 1.25}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("want io.EOF, have %v", err)
+	}
+}
+
+func TestMsgpackDecoder(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		input string
+		want  []interface{}
+	}{
+		// Formats the logger does not produce for these values.
+		{"82a161d0fba162d10005", []interface{}{"a", int64(-5), "b", uint64(5)}},
+		{"81a161da0002686a", []interface{}{"a", "hj"}},
+		{"81a161dc00020102", []interface{}{"a", []interface{}{uint64(1), uint64(2)}}},
+		{"81a161d40107", []interface{}{"a", log.MsgpackExt{Type: 1, Data: []byte{7}}}},
+	} {
+		input, _ := hex.DecodeString(test.input)
+		have, err := log.NewMsgpackDecoder(bytes.NewReader(input)).Decode()
+		if err != nil {
+			t.Errorf("%s: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.want, have) {
+			t.Errorf("%s:\nwant %#v\nhave %#v", test.input, test.want, have)
+		}
+	}
+
+	for _, test := range []struct {
+		input string
+		want  error
+	}{
+		{"82a16b01", io.ErrUnexpectedEOF},
+		{"81a16bce0102", io.ErrUnexpectedEOF},
+		{"", io.EOF},
+	} {
+		input, _ := hex.DecodeString(test.input)
+		if _, err := log.NewMsgpackDecoder(bytes.NewReader(input)).Decode(); err != test.want {
+			t.Errorf("%s: want %v, have %v", test.input, test.want, err)
+		}
+	}
+	if _, err := log.NewMsgpackDecoder(bytes.NewReader([]byte{0x01})).Decode(); err == nil {
+		t.Error("want error decoding a non-map log event")
+	}
+}
+
+func TestMsgpackDecoderValue(t *testing.T) {
+	t.Parallel()
+	input, _ := hex.DecodeString("92a1780193")
+	dec := log.NewMsgpackDecoder(bytes.NewReader(input))
+	have, err := dec.DecodeValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"x", uint64(1)}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+	if _, err := dec.DecodeValue(); err != io.ErrUnexpectedEOF {
+		t.Errorf("want io.ErrUnexpectedEOF, have %v", err)
+	}
+}
+
+func TestMsgpackLoggerConcurrency(t *testing.T) {
+	t.Parallel()
+	testConcurrency(t, log.NewMsgpackLogger(ioutil.Discard), 10000)
+}
+
+func BenchmarkMsgpackLoggerSimple(b *testing.B) {
+	benchmarkRunner(b, log.NewMsgpackLogger(ioutil.Discard), baseMessage)
+}
+
+func BenchmarkMsgpackLoggerContextual(b *testing.B) {
+	benchmarkRunner(b, log.NewMsgpackLogger(ioutil.Discard), withMessage)
+}
+
+func BenchmarkMsgpackLoggerTyped(b *testing.B) {
+	benchmarkRunner(b, log.NewMsgpackLogger(ioutil.Discard), typedMessage)
+}
@@ -0,0 +1,224 @@
+// Package otel provides a Logger that converts log events to the
+// OpenTelemetry Logs Data Model and exports them in batches to an OTLP/HTTP
+// endpoint, such as an OpenTelemetry Collector, using the JSON encoding.
+//
+// Log never blocks on the network: log events are queued and exported from
+// a background goroutine, which retries failed exports with exponential
+// backoff. Call Shutdown before the program exits to export the queued log
+// events.
+//
+//	exporter := otel.NewExporter("http://localhost:4318/v1/logs",
+//		otel.Resource("service.name", "checkout"),
+//	)
+//	defer exporter.Shutdown(context.Background())
+//	logger := log.With(exporter, "ts", log.DefaultTimestampUTC)
+package otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/internal/batch"
+)
+
+// Errors returned by Log.
+var (
+	ErrQueueFull = batch.ErrQueueFull // the queue is full
+	ErrClosed    = batch.ErrClosed    // Shutdown has been called
+)
+
+// scopeName identifies this package as the instrumentation scope of the
+// exported log records.
+const scopeName = "github.com/go-kit/log/otel"
+
+// Exporter is a Logger that exports log events to an OTLP/HTTP endpoint.
+// See NewExporter for details.
+type Exporter struct {
+	url      string
+	client   *http.Client
+	header   http.Header
+	resource []keyValue
+	traceKey interface{}
+	spanKey  interface{}
+	cfg      batch.Config
+	batcher  *batch.Batcher
+}
+
+// Option sets a parameter for an Exporter.
+type Option func(*Exporter)
+
+// HTTPClient sets the client used to send export requests. The default is a
+// client with a 10 second timeout.
+func HTTPClient(c *http.Client) Option {
+	return func(e *Exporter) { e.client = c }
+}
+
+// Header adds a header, such as one holding credentials, to every export
+// request.
+func Header(key, value string) Option {
+	return func(e *Exporter) { e.header.Add(key, value) }
+}
+
+// Resource adds attributes describing the entity producing the logs, such as
+// "service.name", to every export request. The keyvals are converted like
+// log event attributes.
+func Resource(keyvals ...interface{}) Option {
+	return func(e *Exporter) {
+		for i := 0; i < len(keyvals); i += 2 {
+			e.resource = append(e.resource, attribute(keyvals, i))
+		}
+	}
+}
+
+// TraceKey sets the key whose value becomes the trace ID of a log record. The
+// default is "trace_id".
+func TraceKey(key interface{}) Option {
+	return func(e *Exporter) { e.traceKey = key }
+}
+
+// SpanKey sets the key whose value becomes the span ID of a log record. The
+// default is "span_id".
+func SpanKey(key interface{}) Option {
+	return func(e *Exporter) { e.spanKey = key }
+}
+
+// BatchSize sets the maximum number of log records in an export request, 500
+// by default.
+func BatchSize(n int) Option {
+	return batchOption(batch.MaxSize(n))
+}
+
+// FlushInterval sets how often a partial batch is exported, one second by
+// default.
+func FlushInterval(d time.Duration) Option {
+	return batchOption(batch.Interval(d))
+}
+
+// QueueSize sets the maximum number of queued log records, 10000 by default.
+func QueueSize(n int) Option {
+	return batchOption(batch.QueueSize(n))
+}
+
+// Retry sets how failed exports are retried. By default they are retried 5
+// times, after delays from 100ms to 10s.
+func Retry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return batchOption(batch.Retry(maxRetries, minBackoff, maxBackoff))
+}
+
+// ErrorHandler sets the function that receives export errors, which are
+// discarded by default.
+func ErrorHandler(f func(error)) Option {
+	return batchOption(batch.ErrorHandler(f))
+}
+
+// batchOption returns an Option that applies o to the batch.Config of the
+// Exporter.
+func batchOption(o batch.Option) Option {
+	return func(e *Exporter) { o(&e.cfg) }
+}
+
+// NewExporter returns an Exporter that sends log records to url, the full
+// URL of an OTLP/HTTP logs endpoint, usually ending in /v1/logs. It starts a
+// background goroutine, which Shutdown stops.
+//
+// Each log event becomes a log record as follows. A level.Value, or the name
+// of a level, under level.Key() sets the severity number and text; debug,
+// info, warn and error map to the DEBUG, INFO, WARN and ERROR severity
+// numbers, and a few other common names such as trace, notice and fatal to
+// their nearest equivalent. The "msg" value becomes the body, and a
+// time.Time or timestamp Valuer value under "ts" the timestamp. Trace and
+// span IDs, given as hex strings or byte slices or arrays, are taken from
+// the keys set by TraceKey and SpanKey. All other pairs become attributes.
+// Attribute values that are strings, booleans, integers, floats, byte slices,
+// slices or maps keep their type; other values are converted to strings.
+func NewExporter(url string, options ...Option) *Exporter {
+	e := &Exporter{
+		url:      url,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		header:   http.Header{},
+		traceKey: "trace_id",
+		spanKey:  "span_id",
+	}
+	for _, option := range options {
+		option(e)
+	}
+	e.batcher = batch.New(e.export, e.cfg)
+	return e
+}
+
+// Log converts keyvals to a log record and queues it for export. It does not
+// block, and returns ErrQueueFull if the queue is full.
+func (e *Exporter) Log(keyvals ...interface{}) error {
+	return e.batcher.Add(e.record(keyvals))
+}
+
+// Dropped returns the number of log events Log dropped with ErrQueueFull.
+func (e *Exporter) Dropped() uint64 {
+	return e.batcher.Dropped()
+}
+
+// Flush blocks until the log events passed to Log before it have been
+// exported or given up on. It returns ctx.Err() if ctx is done first.
+func (e *Exporter) Flush(ctx context.Context) error {
+	return e.batcher.Flush(ctx)
+}
+
+// Shutdown stops accepting log events and blocks until the queued log events
+// have been exported or given up on. If ctx is done first, Shutdown cancels
+// the pending exports and returns ctx.Err().
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	return e.batcher.Shutdown(ctx)
+}
+
+// export sends one batch of log records.
+func (e *Exporter) export(ctx context.Context, items []interface{}) error {
+	records := make([]logRecord, len(items))
+	for i, item := range items {
+		records[i] = item.(logRecord)
+	}
+	body, err := json.Marshal(exportRequest{
+		ResourceLogs: []resourceLogs{{
+			Resource: resource{Attributes: e.resource},
+			ScopeLogs: []scopeLogs{{
+				Scope:      scope{Name: scopeName},
+				LogRecords: records,
+			}},
+		}},
+	})
+	if err != nil {
+		return batch.Permanent(fmt.Errorf("otel: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return batch.Permanent(fmt.Errorf("otel: %w", err))
+	}
+	for k, v := range e.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otel: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable,
+		resp.StatusCode == http.StatusGatewayTimeout:
+		// The OTLP specification defines these as retryable.
+		return fmt.Errorf("otel: export failed: %s", resp.Status)
+	}
+	return batch.Permanent(fmt.Errorf("otel: export failed: %s", resp.Status))
+}
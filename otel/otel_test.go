@@ -0,0 +1,236 @@
+package otel_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-kit/log/otel"
+)
+
+// collector is an OTLP/HTTP endpoint that records the requests it receives
+// and responds with the status codes in statuses, in order, before
+// responding with 200.
+type collector struct {
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	headers  []http.Header
+	statuses []int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.statuses) > 0 {
+		status := c.statuses[0]
+		c.statuses = c.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header)
+}
+
+func (c *collector) records(t *testing.T) []interface{} {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var records []interface{}
+	for _, req := range c.requests {
+		rl := req["resourceLogs"].([]interface{})[0].(map[string]interface{})
+		sl := rl["scopeLogs"].([]interface{})[0].(map[string]interface{})
+		records = append(records, sl["logRecords"].([]interface{})...)
+	}
+	return records
+}
+
+func TestExporter(t *testing.T) {
+	t.Parallel()
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exporter := otel.NewExporter(srv.URL+"/v1/logs",
+		otel.Resource("service.name", "test"),
+		otel.Header("Authorization", "Bearer x"),
+		otel.FlushInterval(time.Hour),
+	)
+	ts := time.Unix(1700000000, 5)
+	logger := log.With(exporter, "ts", log.TimestampFormat(func() time.Time { return ts }, time.RFC3339))
+	err := level.Warn(logger).Log(
+		"msg", "disk low",
+		"trace_id", "0102030405060708090A0B0C0D0E0F10",
+		"span_id", []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		"free", 0.5,
+		"count", 3,
+		"ok", true,
+		"err", errors.New("boom"),
+		"tags", []string{"a", "b"},
+		"dims", map[string]int{"w": 1},
+		"odd",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	records := c.records(t)
+	if len(records) != 1 {
+		t.Fatalf("want 1 record, have %d", len(records))
+	}
+	record := records[0].(map[string]interface{})
+	if _, ok := record["observedTimeUnixNano"]; !ok {
+		t.Error("no observedTimeUnixNano")
+	}
+	delete(record, "observedTimeUnixNano")
+	have, _ := json.Marshal(record)
+	want := `{"attributes":[` +
+		`{"key":"free","value":{"doubleValue":0.5}},` +
+		`{"key":"count","value":{"intValue":"3"}},` +
+		`{"key":"ok","value":{"boolValue":true}},` +
+		`{"key":"err","value":{"stringValue":"boom"}},` +
+		`{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"stringValue":"b"}]}}},` +
+		`{"key":"dims","value":{"kvlistValue":{"values":[{"key":"w","value":{"intValue":"1"}}]}}},` +
+		`{"key":"odd","value":{"stringValue":"(MISSING)"}}],` +
+		`"body":{"stringValue":"disk low"},"severityNumber":13,"severityText":"warn",` +
+		`"spanId":"0102030405060708","timeUnixNano":"1700000000000000005","traceId":"0102030405060708090a0b0c0d0e0f10"}`
+	if want != string(have) {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if want, have := "Bearer x", c.headers[0].Get("Authorization"); want != have {
+		t.Errorf("Authorization: want %q, have %q", want, have)
+	}
+	if want, have := "application/json", c.headers[0].Get("Content-Type"); want != have {
+		t.Errorf("Content-Type: want %q, have %q", want, have)
+	}
+	resource, _ := json.Marshal(c.requests[0]["resourceLogs"].([]interface{})[0].(map[string]interface{})["resource"])
+	if want, have := `{"attributes":[{"key":"service.name","value":{"stringValue":"test"}}]}`, string(resource); want != have {
+		t.Errorf("resource: want %s, have %s", want, have)
+	}
+}
+
+func TestExporterCopiesBytes(t *testing.T) {
+	t.Parallel()
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exporter := otel.NewExporter(srv.URL, otel.FlushInterval(time.Hour))
+	defer exporter.Shutdown(context.Background())
+	buf := []byte("abc")
+	exporter.Log("data", buf)
+	copy(buf, "xyz")
+	exporter.Flush(context.Background())
+
+	records := c.records(t)
+	if len(records) != 1 {
+		t.Fatalf("want 1 record, have %d", len(records))
+	}
+	have, _ := json.Marshal(records[0].(map[string]interface{})["attributes"])
+	if want := `[{"key":"data","value":{"bytesValue":"YWJj"}}]`; want != string(have) {
+		t.Errorf("\nwant %s\nhave %s", want, have)
+	}
+}
+
+func TestExporterRetry(t *testing.T) {
+	t.Parallel()
+	c := &collector{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exporter := otel.NewExporter(srv.URL, otel.FlushInterval(time.Hour), otel.Retry(5, time.Millisecond, time.Millisecond))
+	defer exporter.Shutdown(context.Background())
+	exporter.Log("msg", "x")
+	exporter.Flush(context.Background())
+	if want, have := 1, len(c.records(t)); want != have {
+		t.Errorf("want %d records, have %d", want, have)
+	}
+}
+
+func TestExporterPermanentError(t *testing.T) {
+	t.Parallel()
+	c := &collector{statuses: []int{http.StatusBadRequest}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	exporter := otel.NewExporter(srv.URL,
+		otel.FlushInterval(time.Hour),
+		otel.Retry(5, time.Millisecond, time.Millisecond),
+		otel.ErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}),
+	)
+	defer exporter.Shutdown(context.Background())
+	exporter.Log("msg", "x")
+	exporter.Flush(context.Background())
+
+	if want, have := 0, len(c.records(t)); want != have {
+		t.Errorf("want %d records, have %d", want, have)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 {
+		t.Errorf("want 1 error, have %v", errs)
+	}
+}
+
+func TestExporterDoesNotBlock(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	exporter := otel.NewExporter(srv.URL, otel.BatchSize(1), otel.QueueSize(1))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			exporter.Log("msg", i)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Log blocked")
+	}
+	if exporter.Dropped() == 0 {
+		t.Error("want dropped log events")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if want, have := context.DeadlineExceeded, exporter.Shutdown(ctx); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := otel.ErrClosed, exporter.Log("msg", "late"); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
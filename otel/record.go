@@ -0,0 +1,291 @@
+package otel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/fields"
+	"github.com/go-kit/log/level"
+)
+
+// The types below are the parts of the OTLP/JSON logs export request that
+// the Exporter uses. The JSON encoding of OTLP encodes 64-bit integers as
+// strings, and trace and span IDs as hex strings.
+
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber,omitempty"`
+	SeverityText         string     `json:"severityText,omitempty"`
+	Body                 *anyValue  `json:"body,omitempty"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string      `json:"stringValue,omitempty"`
+	BoolValue   *bool        `json:"boolValue,omitempty"`
+	IntValue    *string      `json:"intValue,omitempty"`
+	DoubleValue *float64     `json:"doubleValue,omitempty"`
+	BytesValue  []byte       `json:"bytesValue,omitempty"`
+	ArrayValue  *arrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *kvlistValue `json:"kvlistValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
+
+type kvlistValue struct {
+	Values []keyValue `json:"values"`
+}
+
+// Severity numbers from the OpenTelemetry Logs Data Model.
+const (
+	severityTrace  = 1
+	severityDebug  = 5
+	severityInfo   = 9
+	severityInfo2  = 10
+	severityWarn   = 13
+	severityError  = 17
+	severityError2 = 18
+	severityError3 = 19
+	severityFatal  = 21
+)
+
+// severities maps levels to severity numbers.
+var severities = map[fields.Level]int{
+	fields.LevelTrace:     severityTrace,
+	fields.LevelDebug:     severityDebug,
+	fields.LevelInfo:      severityInfo,
+	fields.LevelNotice:    severityInfo2,
+	fields.LevelWarn:      severityWarn,
+	fields.LevelError:     severityError,
+	fields.LevelCritical:  severityError2,
+	fields.LevelAlert:     severityError3,
+	fields.LevelFatal:     severityFatal,
+	fields.LevelEmergency: severityFatal,
+}
+
+// maxValueDepth limits how deeply nested values are converted, which guards
+// against cyclic data structures.
+const maxValueDepth = 10
+
+// record converts a log event to a log record.
+func (e *Exporter) record(keyvals []interface{}) logRecord {
+	r := logRecord{ObservedTimeUnixNano: unixNano(time.Now())}
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		switch k {
+		case level.Key():
+			if number, text, ok := severity(v); ok {
+				r.SeverityNumber, r.SeverityText = number, text
+				continue
+			}
+		case "msg":
+			body := value(v, 0)
+			r.Body = &body
+			continue
+		case "ts":
+			if t, ok := fields.Time(v); ok {
+				r.TimeUnixNano = unixNano(t)
+				continue
+			}
+		}
+		switch {
+		case k == e.traceKey:
+			if id, ok := hexID(v, 16); ok {
+				r.TraceID = id
+				continue
+			}
+		case k == e.spanKey:
+			if id, ok := hexID(v, 8); ok {
+				r.SpanID = id
+				continue
+			}
+		}
+		r.Attributes = append(r.Attributes, attribute(keyvals, i))
+	}
+	return r
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// severity returns the severity number and text of the level value v.
+func severity(v interface{}) (number int, text string, ok bool) {
+	text, ok = v.(string)
+	if !ok {
+		text = fmt.Sprint(v)
+	}
+	l, ok := fields.ParseLevel(text)
+	if !ok {
+		return 0, "", false
+	}
+	return severities[l], text, true
+}
+
+// hexID returns v, a trace or span ID of size bytes, as a lowercase hex
+// string.
+func hexID(v interface{}, size int) (string, bool) {
+	var b []byte
+	switch x := v.(type) {
+	case string:
+		if len(x) != 2*size {
+			return "", false
+		}
+		if _, err := hex.DecodeString(x); err != nil {
+			return "", false
+		}
+		return strings.ToLower(x), true
+	case []byte:
+		b = x
+	case [16]byte:
+		b = x[:]
+	case [8]byte:
+		b = x[:]
+	default:
+		return "", false
+	}
+	if len(b) != size {
+		return "", false
+	}
+	return hex.EncodeToString(b), true
+}
+
+// attribute converts the pair at keyvals[i].
+func attribute(keyvals []interface{}, i int) keyValue {
+	var v interface{} = log.ErrMissingValue
+	if i+1 < len(keyvals) {
+		v = keyvals[i+1]
+	}
+	return keyValue{Key: keyString(keyvals[i]), Value: value(v, 0)}
+}
+
+func keyString(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprint(k)
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+func intValue(n int64) anyValue {
+	s := strconv.FormatInt(n, 10)
+	return anyValue{IntValue: &s}
+}
+
+func doubleValue(f float64) anyValue {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// JSON has no representation of these.
+		return stringValue(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return anyValue{DoubleValue: &f}
+}
+
+// value converts v to an OTLP AnyValue.
+func value(v interface{}, depth int) anyValue {
+	switch x := v.(type) {
+	case nil:
+		return anyValue{}
+	case string:
+		return stringValue(x)
+	case bool:
+		return anyValue{BoolValue: &x}
+	case []byte:
+		// The record is encoded later by the background goroutine, so it
+		// must not share the caller's buffer.
+		return anyValue{BytesValue: append([]byte(nil), x...)}
+	case time.Time:
+		return stringValue(x.Format(time.RFC3339Nano))
+	case error, fmt.Stringer:
+		return stringValue(fmt.Sprint(x))
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intValue(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return intValue(int64(u))
+		}
+		return stringValue(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return doubleValue(rv.Float())
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return anyValue{}
+		}
+		if depth < maxValueDepth {
+			return value(rv.Elem().Interface(), depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		if depth >= maxValueDepth || rv.Kind() == reflect.Slice && rv.IsNil() {
+			break
+		}
+		a := &arrayValue{Values: make([]anyValue, rv.Len())}
+		for i := range a.Values {
+			a.Values[i] = value(rv.Index(i).Interface(), depth+1)
+		}
+		return anyValue{ArrayValue: a}
+	case reflect.Map:
+		if depth >= maxValueDepth || rv.IsNil() {
+			break
+		}
+		kv := &kvlistValue{Values: make([]keyValue, 0, rv.Len())}
+		iter := rv.MapRange()
+		for iter.Next() {
+			kv.Values = append(kv.Values, keyValue{
+				Key:   keyString(iter.Key().Interface()),
+				Value: value(iter.Value().Interface(), depth+1),
+			})
+		}
+		sort.Slice(kv.Values, func(i, j int) bool { return kv.Values[i].Key < kv.Values[j].Key })
+		return anyValue{KvlistValue: kv}
+	}
+	return stringValue(fmt.Sprint(v))
+}
@@ -0,0 +1,216 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate of log events passed to a wrapped Logger. See
+// NewRateLimiter for details.
+type RateLimiter struct {
+	next            Logger
+	keys            []string
+	rate            float64
+	burst           float64
+	summaryInterval time.Duration
+	now             func() time.Time
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastSummary time.Time
+}
+
+// bucket is the token bucket of one group of log events.
+type bucket struct {
+	group      []interface{} // the group keys and their values
+	tokens     float64
+	last       time.Time
+	suppressed uint64
+	level      interface{}
+}
+
+// NewRateLimiter returns a Logger that limits the rate of log events passed
+// to next. Log events are grouped by the values of the keys set with
+// RateLimitKeys, and each group has its own token bucket. Log events that
+// find their bucket empty are discarded without error and counted.
+//
+// At most once per summary interval the Logger reports the log events it
+// discarded for each group with a summary log event such as:
+//
+//	level=error msg="rate limited" component=db suppressed=1234
+//
+// The summary carries the level value of the last discarded log event, if
+// any, so that it passes through the same level filters. A "msg" group key
+// is reported as "suppressed_msg". Summaries are written from within calls
+// to Log; no background goroutine is started. Call Flush before the program
+// exits to write the summaries of log events discarded since the last one.
+func NewRateLimiter(next Logger, options ...RateLimitOption) *RateLimiter {
+	l := &RateLimiter{
+		next:            next,
+		keys:            []string{"msg"},
+		rate:            10,
+		burst:           10,
+		summaryInterval: 10 * time.Second,
+		now:             time.Now,
+		buckets:         map[string]*bucket{},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	l.lastSummary = l.now()
+	return l
+}
+
+// Log passes keyvals to the wrapped Logger unless their bucket is empty,
+// after writing any summaries that are due.
+func (l *RateLimiter) Log(keyvals ...interface{}) error {
+	id, group := l.group(keyvals)
+	now := l.now()
+
+	l.mu.Lock()
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{group: group, tokens: l.burst, last: now}
+		l.buckets[id] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	var summaries [][]interface{}
+	if now.Sub(l.lastSummary) >= l.summaryInterval {
+		l.lastSummary = now
+		summaries = l.summarize(now)
+		// summarize may have forgotten b if it was full.
+		l.buckets[id] = b
+	}
+	allow := b.tokens >= 1
+	if allow {
+		b.tokens--
+	} else {
+		b.suppressed++
+		if lvl, ok := lookup(keyvals, "level"); ok {
+			b.level = lvl
+		}
+	}
+	l.mu.Unlock()
+
+	err := l.write(summaries)
+	if allow {
+		if lerr := l.next.Log(keyvals...); lerr != nil {
+			err = lerr
+		}
+	}
+	return err
+}
+
+// Flush writes the summaries of the log events discarded since the last
+// summary, if any, and starts a new summary interval.
+func (l *RateLimiter) Flush() error {
+	now := l.now()
+	l.mu.Lock()
+	l.lastSummary = now
+	summaries := l.summarize(now)
+	l.mu.Unlock()
+	return l.write(summaries)
+}
+
+// write passes summaries to the wrapped Logger and returns the first error.
+func (l *RateLimiter) write(summaries [][]interface{}) error {
+	var err error
+	for _, kvs := range summaries {
+		if serr := l.next.Log(kvs...); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// group returns a map key identifying the group of keyvals and the group
+// keys and values found in keyvals.
+func (l *RateLimiter) group(keyvals []interface{}) (string, []interface{}) {
+	var id strings.Builder
+	group := make([]interface{}, 0, 2*len(l.keys))
+	for _, k := range l.keys {
+		v, ok := lookup(keyvals, k)
+		if ok {
+			id.WriteString(keyString(v))
+			if k == "msg" {
+				group = append(group, "suppressed_msg", v)
+			} else {
+				group = append(group, k, v)
+			}
+		}
+		id.WriteByte(0)
+	}
+	return id.String(), group
+}
+
+// summarize returns summary log events, ordered by group, for the buckets
+// with discarded log events and resets their counts. It also forgets buckets
+// that have been idle long enough to refill, which bounds memory use. The
+// caller must hold l.mu.
+func (l *RateLimiter) summarize(now time.Time) [][]interface{} {
+	var ids []string
+	for id, b := range l.buckets {
+		if b.suppressed > 0 {
+			ids = append(ids, id)
+		} else if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, id)
+		}
+	}
+	sort.Strings(ids)
+
+	summaries := make([][]interface{}, 0, len(ids))
+	for _, id := range ids {
+		b := l.buckets[id]
+		kvs := make([]interface{}, 0, 6+len(b.group))
+		if b.level != nil {
+			kvs = append(kvs, "level", b.level)
+		}
+		kvs = append(kvs, "msg", "rate limited")
+		kvs = append(kvs, b.group...)
+		kvs = append(kvs, "suppressed", b.suppressed)
+		summaries = append(summaries, kvs)
+		b.suppressed = 0
+		b.level = nil
+	}
+	return summaries
+}
+
+// RateLimitOption sets a parameter for a RateLimiter.
+type RateLimitOption func(*RateLimiter)
+
+// RateLimit sets the rate, in log events per second, at which tokens are
+// added to each bucket, and the number of tokens a bucket holds when full.
+// A full bucket allows a burst of that many log events. The default is 10
+// log events per second with a burst of 10.
+func RateLimit(perSecond float64, burst int) RateLimitOption {
+	return func(l *RateLimiter) {
+		l.rate = perSecond
+		l.burst = float64(burst)
+	}
+}
+
+// RateLimitKeys sets the keys whose values group log events into buckets.
+// Log events that lack a key are grouped as if the key had an empty value.
+// The default is to group log events by their "msg" value. Passing no keys
+// puts all log events in one bucket.
+func RateLimitKeys(keys ...string) RateLimitOption {
+	return func(l *RateLimiter) { l.keys = keys }
+}
+
+// RateLimitSummaryInterval sets the minimum time between summaries of
+// discarded log events. The default is ten seconds.
+func RateLimitSummaryInterval(d time.Duration) RateLimitOption {
+	return func(l *RateLimiter) { l.summaryInterval = d }
+}
+
+// RateLimitClock sets the function that returns the current time, which
+// refills the buckets and times the summaries. The default is time.Now.
+func RateLimitClock(now func() time.Time) RateLimitOption {
+	return func(l *RateLimiter) { l.now = now }
+}
@@ -0,0 +1,151 @@
+package log_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// fakeClock is a clock for RateLimitClock that moves only when advanced.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	logger := log.NewRateLimiter(log.NewLogfmtLogger(buf),
+		log.RateLimit(1, 2),
+		log.RateLimitKeys("component"),
+		log.RateLimitSummaryInterval(time.Hour),
+		log.RateLimitClock(clock.Now),
+	)
+
+	for i := 0; i < 5; i++ {
+		if err := logger.Log("component", "db", "i", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logger.Log("component", "cache", "i", 0)
+	clock.Advance(time.Second)
+	logger.Log("component", "db", "i", 5)
+	logger.Log("component", "db", "i", 6)
+
+	want := strings.Join([]string{
+		"component=db i=0",
+		"component=db i=1",
+		"component=cache i=0",
+		"component=db i=5",
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+func TestRateLimiterSummary(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	logger := log.NewRateLimiter(log.NewLogfmtLogger(buf),
+		log.RateLimit(0.001, 1),
+		log.RateLimitSummaryInterval(time.Minute),
+		log.RateLimitClock(clock.Now),
+	)
+
+	for i := 0; i < 4; i++ {
+		logger.Log("msg", "timeout", "i", i)
+	}
+	clock.Advance(time.Minute)
+	logger.Log("msg", "other")
+
+	want := strings.Join([]string{
+		"msg=timeout i=0",
+		`msg="rate limited" suppressed_msg=timeout suppressed=3`,
+		"msg=other",
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+// TestRateLimiterFlush checks that Flush writes the summary when no log
+// events follow the discarded ones.
+func TestRateLimiterFlush(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	logger := log.NewRateLimiter(log.NewLogfmtLogger(buf),
+		log.RateLimit(0.001, 1),
+		log.RateLimitSummaryInterval(time.Minute),
+		log.RateLimitClock(clock.Now),
+	)
+
+	for i := 0; i < 3; i++ {
+		logger.Log("msg", "timeout", "i", i)
+	}
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"msg=timeout i=0",
+		`msg="rate limited" suppressed_msg=timeout suppressed=2`,
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+func TestRateLimiterComposition(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var logger log.Logger
+	logger = log.NewLogfmtLogger(buf)
+	logger = level.NewFilter(logger, level.AllowWarn())
+	logger = log.NewRateLimiter(logger,
+		log.RateLimit(0.001, 1),
+		log.RateLimitKeys("component"),
+		log.RateLimitSummaryInterval(time.Minute),
+		log.RateLimitClock(clock.Now),
+	)
+	logger = log.With(logger, "component", "db")
+
+	level.Error(logger).Log("msg", "down")
+	level.Error(logger).Log("msg", "down")
+	level.Error(logger).Log("msg", "down")
+	clock.Advance(time.Minute)
+	// Debug events are filtered after the rate limiter, but still trigger
+	// the summary of the error events, which passes the filter.
+	level.Debug(logger).Log("msg", "noise")
+
+	want := strings.Join([]string{
+		"level=error component=db msg=down",
+		`level=error msg="rate limited" component=db suppressed=2`,
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+func TestRateLimiterConcurrency(t *testing.T) {
+	t.Parallel()
+	testConcurrency(t, log.NewRateLimiter(log.NewLogfmtLogger(ioutil.Discard)), 10000)
+}
+
+func BenchmarkRateLimiterSimple(b *testing.B) {
+	benchmarkRunner(b, log.NewRateLimiter(log.NewLogfmtLogger(ioutil.Discard)), baseMessage)
+}
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NewSampler returns a Logger that passes a sample of log events to next.
+// Log events are grouped by a sampling key. In each sampling interval the
+// first log events for a key are passed to next, after which only every Mth
+// log event for that key is passed on and the rest are discarded without
+// error. See the SamplerOption functions for the defaults.
+//
+// The default sampling key combines the values of the "msg" and "level"
+// keys, so that a log site that emits the same message many times is sampled
+// without affecting other messages at the same level.
+func NewSampler(next Logger, options ...SamplerOption) Logger {
+	l := &sampler{
+		next:       next,
+		first:      100,
+		thereafter: 100,
+		interval:   time.Second,
+		key:        defaultSampleKey,
+		counts:     map[string]uint64{},
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+type sampler struct {
+	next       Logger
+	first      uint64
+	thereafter uint64
+	interval   time.Duration
+	key        func(keyvals ...interface{}) string
+
+	mu     sync.Mutex
+	reset  time.Time
+	counts map[string]uint64
+}
+
+func (l *sampler) Log(keyvals ...interface{}) error {
+	if !l.sample(l.key(keyvals...)) {
+		return nil
+	}
+	return l.next.Log(keyvals...)
+}
+
+func (l *sampler) sample(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forgetting every key at the end of the interval, rather than tracking
+	// an interval per key, bounds memory use to the number of distinct keys
+	// seen in one interval.
+	if now := time.Now(); !now.Before(l.reset) {
+		l.reset = now.Add(l.interval)
+		for k := range l.counts {
+			delete(l.counts, k)
+		}
+	}
+
+	n := l.counts[key] + 1
+	l.counts[key] = n
+	if n <= l.first {
+		return true
+	}
+	return l.thereafter > 0 && (n-l.first)%l.thereafter == 0
+}
+
+// SamplerOption sets a parameter for the Logger returned by NewSampler.
+type SamplerOption func(*sampler)
+
+// SampleFirst sets the number of log events per key passed to the wrapped
+// Logger in each interval before sampling starts. The default is 100.
+func SampleFirst(n int) SamplerOption {
+	return func(l *sampler) {
+		if n >= 0 {
+			l.first = uint64(n)
+		}
+	}
+}
+
+// SampleThereafter sets the sampling rate once the number of log events set
+// by SampleFirst has been reached: every mth log event for a key is passed to
+// the wrapped Logger. If m is zero all further log events for the key in the
+// interval are discarded. The default is 100.
+func SampleThereafter(m int) SamplerOption {
+	return func(l *sampler) {
+		if m >= 0 {
+			l.thereafter = uint64(m)
+		}
+	}
+}
+
+// SampleInterval sets the duration after which the count of log events for
+// every key starts over. The default is one second. Values less than or
+// equal to zero are ignored.
+func SampleInterval(d time.Duration) SamplerOption {
+	return func(l *sampler) {
+		if d > 0 {
+			l.interval = d
+		}
+	}
+}
+
+// SampleKey sets the function that computes the sampling key of a log event.
+// Log events with equal keys are counted together.
+func SampleKey(key func(keyvals ...interface{}) string) SamplerOption {
+	return func(l *sampler) { l.key = key }
+}
+
+func defaultSampleKey(keyvals ...interface{}) string {
+	lvl, _ := lookup(keyvals, "level")
+	msg, _ := lookup(keyvals, "msg")
+	return keyString(lvl) + "\x00" + keyString(msg)
+}
+
+// lookup returns the value of the first occurrence of key in keyvals.
+func lookup(keyvals []interface{}, key string) (interface{}, bool) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if keyvals[i] == key {
+			if i+1 < len(keyvals) {
+				return keyvals[i+1], true
+			}
+			return ErrMissingValue, true
+		}
+	}
+	return nil, false
+}
+
+// keyString formats v for use in a map key.
+func keyString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case fmt.Stringer:
+		return safeString(x)
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -0,0 +1,133 @@
+package log_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestSampler(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewSampler(log.NewLogfmtLogger(buf),
+		log.SampleFirst(2),
+		log.SampleThereafter(3),
+		log.SampleInterval(time.Hour),
+	)
+
+	for i := 1; i <= 10; i++ {
+		if err := logger.Log("level", "debug", "msg", "hot", "i", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A different message at the same level is sampled separately.
+	logger.Log("level", "debug", "msg", "cold", "i", 1)
+	// So is the same message at a different level.
+	logger.Log("level", "info", "msg", "hot", "i", 1)
+
+	want := strings.Join([]string{
+		"level=debug msg=hot i=1",
+		"level=debug msg=hot i=2",
+		"level=debug msg=hot i=5",
+		"level=debug msg=hot i=8",
+		"level=debug msg=cold i=1",
+		"level=info msg=hot i=1",
+	}, "\n") + "\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant:\n%s\nhave:\n%s", want, have)
+	}
+}
+
+func TestSamplerThereafterZero(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewSampler(log.NewLogfmtLogger(buf), log.SampleFirst(1), log.SampleThereafter(0))
+
+	for i := 0; i < 5; i++ {
+		logger.Log("msg", "m", "i", i)
+	}
+	if want, have := "msg=m i=0\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+}
+
+func TestSamplerInterval(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewSampler(log.NewLogfmtLogger(buf),
+		log.SampleFirst(1),
+		log.SampleThereafter(0),
+		log.SampleInterval(20*time.Millisecond),
+	)
+
+	logger.Log("msg", "m", "i", 0)
+	logger.Log("msg", "m", "i", 1)
+	time.Sleep(40 * time.Millisecond)
+	logger.Log("msg", "m", "i", 2)
+
+	if want, have := "msg=m i=0\nmsg=m i=2\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+}
+
+func TestSamplerIntervalNonPositive(t *testing.T) {
+	t.Parallel()
+	for _, d := range []time.Duration{0, -time.Second} {
+		buf := &bytes.Buffer{}
+		logger := log.NewSampler(log.NewLogfmtLogger(buf),
+			log.SampleFirst(1),
+			log.SampleThereafter(0),
+			log.SampleInterval(d),
+		)
+
+		for i := 0; i < 3; i++ {
+			logger.Log("msg", "m", "i", i)
+		}
+		if want, have := "msg=m i=0\n", buf.String(); want != have {
+			t.Errorf("%v: want %#v, have %#v", d, want, have)
+		}
+	}
+}
+
+func TestSamplerKey(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewSampler(log.NewLogfmtLogger(buf),
+		log.SampleFirst(1),
+		log.SampleThereafter(0),
+		log.SampleKey(func(keyvals ...interface{}) string { return "all" }),
+	)
+
+	logger.Log("msg", "a")
+	logger.Log("msg", "b")
+	if want, have := "msg=a\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+}
+
+func TestSamplerWithContext(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log.NewSampler(log.NewLogfmtLogger(buf), log.SampleFirst(1), log.SampleThereafter(0))
+	// The sampling key is read after the context keyvals have been added.
+	logger = log.With(logger, "msg", "from context")
+
+	logger.Log("i", 0)
+	logger.Log("i", 1)
+	if want, have := "msg=\"from context\" i=0\n", buf.String(); want != have {
+		t.Errorf("\nwant %#v\nhave %#v", want, have)
+	}
+}
+
+func TestSamplerConcurrency(t *testing.T) {
+	t.Parallel()
+	testConcurrency(t, log.NewSampler(log.NewLogfmtLogger(ioutil.Discard)), 10000)
+}
+
+func BenchmarkSamplerSimple(b *testing.B) {
+	benchmarkRunner(b, log.NewSampler(log.NewLogfmtLogger(ioutil.Discard)), baseMessage)
+}
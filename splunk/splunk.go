@@ -0,0 +1,386 @@
+// Package splunk provides a Logger that ships log events to a Splunk HTTP
+// Event Collector (HEC).
+//
+// Log never blocks on the network: log events are queued, up to a bounded
+// number, and posted in batches from a background goroutine, which retries
+// failed posts with exponential backoff. Call Close before the program exits
+// to post the queued log events.
+//
+//	client := splunk.NewClient("https://splunk.example.com:8088/services/collector/event", token,
+//		splunk.Source("checkout"),
+//		splunk.Gzip(),
+//	)
+//	defer client.Close()
+//	logger := log.With(client, "ts", log.DefaultTimestampUTC)
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/batch"
+	"github.com/go-kit/log/internal/fields"
+)
+
+// Errors returned by Log.
+var (
+	ErrQueueFull = batch.ErrQueueFull // the queue is full
+	ErrClosed    = batch.ErrClosed    // Close has been called
+)
+
+// Client is a Logger that posts log events to a Splunk HEC. See NewClient
+// for details.
+type Client struct {
+	url        string
+	ackURL     string
+	token      string
+	client     *http.Client
+	gzip       bool
+	channel    string
+	ackTimeout time.Duration
+	envelope   []byte // the fields common to all events
+	host       string
+	meta       map[string]string
+	cfg        batch.Config
+	batcher    *batch.Batcher
+	bufPool    sync.Pool
+}
+
+// Option sets a parameter for a Client.
+type Option func(*Client)
+
+// Host sets the host field of every event. By default it is the host name
+// reported by the operating system.
+func Host(name string) Option {
+	return func(c *Client) { c.host = name }
+}
+
+// Source sets the source field of every event.
+func Source(source string) Option {
+	return func(c *Client) { c.meta["source"] = source }
+}
+
+// SourceType sets the sourcetype field of every event.
+func SourceType(sourcetype string) Option {
+	return func(c *Client) { c.meta["sourcetype"] = sourcetype }
+}
+
+// Index sets the index field of every event.
+func Index(index string) Option {
+	return func(c *Client) { c.meta["index"] = index }
+}
+
+// Gzip enables gzip compression of request bodies.
+func Gzip() Option {
+	return func(c *Client) { c.gzip = true }
+}
+
+// Ack enables indexer acknowledgement, which must also be enabled for the
+// token. After each post the Client polls the HEC until the events are
+// indexed, and posts them again if they are not indexed within timeout, so
+// that events are delivered at least once. The channel identifies the
+// client to the HEC and must be a GUID; if it is empty, a random one is
+// used.
+func Ack(channel string, timeout time.Duration) Option {
+	return func(c *Client) {
+		c.channel = channel
+		c.ackTimeout = timeout
+	}
+}
+
+// HTTPClient sets the client used to send requests. The default is a client
+// with a 10 second timeout.
+func HTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.client = hc }
+}
+
+// BatchSize sets the maximum number of log events in a request, 500 by
+// default.
+func BatchSize(n int) Option {
+	return batchOption(batch.MaxSize(n))
+}
+
+// FlushInterval sets how often a partial batch is posted, one second by
+// default.
+func FlushInterval(d time.Duration) Option {
+	return batchOption(batch.Interval(d))
+}
+
+// QueueSize sets the maximum number of queued log events, 10000 by default.
+func QueueSize(n int) Option {
+	return batchOption(batch.QueueSize(n))
+}
+
+// Retry sets how failed posts are retried. By default they are retried 5
+// times, after delays from 100ms to 10s.
+func Retry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return batchOption(batch.Retry(maxRetries, minBackoff, maxBackoff))
+}
+
+// ErrorHandler sets the function that receives post errors, which are
+// discarded by default.
+func ErrorHandler(f func(error)) Option {
+	return batchOption(batch.ErrorHandler(f))
+}
+
+// batchOption returns an Option that applies o to the batch.Config of the
+// Client.
+func batchOption(o batch.Option) Option {
+	return func(c *Client) { o(&c.cfg) }
+}
+
+// NewClient returns a Client that posts log events to url, the full URL of
+// the HEC event endpoint, usually ending in /services/collector/event, and
+// authenticates with token. It starts a background goroutine, which Close
+// stops.
+//
+// Each log event becomes an event whose event field is a JSON object of its
+// key/value pairs, in the order they were logged, except for a time.Time or
+// timestamp Valuer value under "ts". That sets the time field, in epoch
+// seconds with millisecond precision, which is otherwise the time of the Log
+// call.
+func NewClient(url, token string, options ...Option) *Client {
+	c := &Client{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+		meta:   map[string]string{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.host == "" {
+		c.host, _ = os.Hostname()
+	}
+	if c.ackTimeout > 0 {
+		if c.channel == "" {
+			c.channel = newGUID()
+		}
+		c.ackURL = ackURL(url)
+	}
+
+	// The envelope fields are fixed, so encode them once.
+	c.envelope = append(c.envelope, `"host":`...)
+	c.envelope = appendJSON(c.envelope, c.host)
+	for _, k := range []string{"source", "sourcetype", "index"} {
+		if v, ok := c.meta[k]; ok {
+			c.envelope = append(c.envelope, `,"`+k+`":`...)
+			c.envelope = appendJSON(c.envelope, v)
+		}
+	}
+
+	c.batcher = batch.New(c.post, c.cfg)
+	return c
+}
+
+type eventBuf struct {
+	buf    bytes.Buffer
+	logger log.Logger
+	kvs    []interface{}
+}
+
+// Log encodes keyvals as an event and queues it to be posted. It does not
+// block, and returns ErrQueueFull if the queue is full.
+func (c *Client) Log(keyvals ...interface{}) error {
+	eb, _ := c.bufPool.Get().(*eventBuf)
+	if eb == nil {
+		eb = &eventBuf{}
+		eb.logger = log.NewJSONLoggerWithOptions(&eb.buf, log.JSONPreserveKeyOrder())
+	}
+	defer func() {
+		for i := range eb.kvs {
+			eb.kvs[i] = nil
+		}
+		eb.kvs = eb.kvs[:0]
+		eb.buf.Reset()
+		c.bufPool.Put(eb)
+	}()
+
+	var ts time.Time
+	for i := 0; i < len(keyvals); i += 2 {
+		if keyvals[i] == "ts" && i+1 < len(keyvals) {
+			if t, ok := fields.Time(keyvals[i+1]); ok {
+				ts = t
+				continue
+			}
+		}
+		eb.kvs = append(eb.kvs, keyvals[i])
+		if i+1 < len(keyvals) {
+			eb.kvs = append(eb.kvs, keyvals[i+1])
+		}
+	}
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	if err := eb.logger.Log(eb.kvs...); err != nil {
+		return err
+	}
+
+	event := make([]byte, 0, len(c.envelope)+eb.buf.Len()+32)
+	event = append(event, `{"time":`...)
+	event = strconv.AppendFloat(event, float64(ts.UnixNano()/int64(time.Millisecond))/1e3, 'f', 3, 64)
+	event = append(event, ',')
+	event = append(event, c.envelope...)
+	event = append(event, `,"event":`...)
+	event = append(event, bytes.TrimSuffix(eb.buf.Bytes(), []byte("\n"))...)
+	event = append(event, '}')
+	return c.batcher.Add(event)
+}
+
+// Dropped returns the number of log events Log dropped with ErrQueueFull.
+func (c *Client) Dropped() uint64 {
+	return c.batcher.Dropped()
+}
+
+// Flush blocks until the log events passed to Log before it have been posted
+// or given up on. It returns ctx.Err() if ctx is done first.
+func (c *Client) Flush(ctx context.Context) error {
+	return c.batcher.Flush(ctx)
+}
+
+// Close stops accepting log events and blocks until the queued log events
+// have been posted or given up on.
+func (c *Client) Close() error {
+	return c.batcher.Shutdown(context.Background())
+}
+
+// hecResponse is the body of an HEC response.
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+// post sends one batch of events, which the HEC accepts as concatenated JSON
+// objects.
+func (c *Client) post(ctx context.Context, items []interface{}) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+	var zw *gzip.Writer
+	if c.gzip {
+		zw = gzip.NewWriter(&body)
+		w = zw
+	}
+	for _, item := range items {
+		w.Write(item.([]byte))
+		w.Write([]byte("\n"))
+	}
+	if zw != nil {
+		zw.Close()
+	}
+
+	var resp hecResponse
+	if err := c.do(ctx, c.url, &body, c.gzip, &resp); err != nil {
+		return err
+	}
+	if c.ackTimeout <= 0 {
+		return nil
+	}
+	if resp.AckID == nil {
+		return batch.Permanent(errors.New("splunk: no ackId in response; is indexer acknowledgement enabled for the token?"))
+	}
+	return c.waitForAck(ctx, *resp.AckID)
+}
+
+// waitForAck polls the HEC until the events of the post with the ack ID are
+// indexed, or returns an error after the ack timeout so that they are posted
+// again.
+func (c *Client) waitForAck(ctx context.Context, id int64) error {
+	deadline := time.Now().Add(c.ackTimeout)
+	delay := 50 * time.Millisecond
+	query, _ := json.Marshal(map[string][]int64{"acks": {id}})
+	for {
+		var resp struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		if err := c.do(ctx, c.ackURL, bytes.NewReader(query), false, &resp); err != nil {
+			return err
+		}
+		if resp.Acks[strconv.FormatInt(id, 10)] {
+			return nil
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("splunk: events not acknowledged within %v", c.ackTimeout)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		if delay *= 2; delay > time.Second {
+			delay = time.Second
+		}
+	}
+}
+
+// do posts body to url and decodes the JSON response into v.
+func (c *Client) do(ctx context.Context, url string, body io.Reader, gzipped bool, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return batch.Permanent(fmt.Errorf("splunk: %w", err))
+	}
+	req.Header.Set("Authorization", "Splunk "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if c.channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", c.channel)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("splunk: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("splunk: invalid response: %w", err)
+		}
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("splunk: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return batch.Permanent(fmt.Errorf("splunk: %s: %s", resp.Status, bytes.TrimSpace(data)))
+}
+
+// ackURL returns the URL of the ack endpoint of the HEC with the event
+// endpoint url.
+func ackURL(url string) string {
+	if i := strings.LastIndex(url, "/services/collector"); i >= 0 {
+		return url[:i] + "/services/collector/ack"
+	}
+	return strings.TrimSuffix(url, "/") + "/ack"
+}
+
+// newGUID returns a random (version 4) GUID.
+func newGUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func appendJSON(b []byte, s string) []byte {
+	out, _ := json.Marshal(s)
+	return append(b, out...)
+}
@@ -0,0 +1,200 @@
+package splunk_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/splunk"
+)
+
+// hec is a stand-in for a Splunk HTTP Event Collector. It acknowledges a
+// post after ackPolls polls of the ack endpoint.
+type hec struct {
+	mu       sync.Mutex
+	events   []string
+	headers  []http.Header
+	nextAck  int
+	polls    map[int]int
+	ackPolls int
+}
+
+func (h *hec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r.Header.Get("Authorization") != "Splunk secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"text":"Invalid token","code":4}`)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/ack") {
+		var req struct{ Acks []int }
+		json.NewDecoder(r.Body).Decode(&req)
+		acks := map[string]bool{}
+		for _, id := range req.Acks {
+			h.polls[id]++
+			acks[fmt.Sprint(id)] = h.polls[id] > h.ackPolls
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"acks": acks})
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		h.events = append(h.events, scanner.Text())
+	}
+	h.headers = append(h.headers, r.Header)
+	fmt.Fprintf(w, `{"text":"Success","code":0,"ackId":%d}`, h.nextAck)
+	h.nextAck++
+}
+
+func newHEC() (*hec, *httptest.Server) {
+	h := &hec{polls: map[int]int{}}
+	return h, httptest.NewServer(h)
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	h, srv := newHEC()
+	defer srv.Close()
+
+	client := splunk.NewClient(srv.URL+"/services/collector/event", "secret",
+		splunk.Host("web-1"),
+		splunk.Source("app"),
+		splunk.SourceType("_json"),
+		splunk.Index("main"),
+		splunk.Gzip(),
+		splunk.FlushInterval(time.Hour),
+	)
+	ts := time.Unix(1700000000, 123456789)
+	logger := log.With(client, "ts", log.TimestampFormat(func() time.Time { return ts }, time.RFC3339))
+	logger.Log("msg", "hello", "n", 1)
+	client.Log("ts", ts, "msg", "direct")
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	want := []string{
+		`{"time":1700000000.123,"host":"web-1","source":"app","sourcetype":"_json","index":"main","event":{"msg":"hello","n":1}}`,
+		`{"time":1700000000.123,"host":"web-1","source":"app","sourcetype":"_json","index":"main","event":{"msg":"direct"}}`,
+	}
+	if strings.Join(want, "\n") != strings.Join(h.events, "\n") {
+		t.Errorf("\nwant %s\nhave %s", want, h.events)
+	}
+	if want, have := "gzip", h.headers[0].Get("Content-Encoding"); want != have {
+		t.Errorf("Content-Encoding: want %q, have %q", want, have)
+	}
+}
+
+func TestClientAck(t *testing.T) {
+	t.Parallel()
+	h, srv := newHEC()
+	defer srv.Close()
+	h.ackPolls = 2
+
+	client := splunk.NewClient(srv.URL+"/services/collector/event", "secret",
+		splunk.Ack("", 5*time.Second),
+		splunk.FlushInterval(time.Hour),
+	)
+	client.Log("msg", "acked")
+	client.Close()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if want, have := 1, len(h.events); want != have {
+		t.Fatalf("want %d events, have %d", want, have)
+	}
+	if want, have := 3, h.polls[0]; want != have {
+		t.Errorf("want %d polls, have %d", want, have)
+	}
+	if h.headers[0].Get("X-Splunk-Request-Channel") == "" {
+		t.Error("no channel header")
+	}
+}
+
+func TestClientAckTimeout(t *testing.T) {
+	t.Parallel()
+	h, srv := newHEC()
+	defer srv.Close()
+	h.ackPolls = 1000
+
+	client := splunk.NewClient(srv.URL+"/services/collector/event", "secret",
+		splunk.Ack("00000000-0000-4000-8000-000000000000", 10*time.Millisecond),
+		splunk.Retry(1, time.Millisecond, time.Millisecond),
+		splunk.FlushInterval(time.Hour),
+	)
+	client.Log("msg", "lost")
+	client.Close()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Unacknowledged events are posted again.
+	if want, have := 2, len(h.events); want != have {
+		t.Errorf("want %d events, have %d", want, have)
+	}
+	if want, have := "00000000-0000-4000-8000-000000000000", h.headers[0].Get("X-Splunk-Request-Channel"); want != have {
+		t.Errorf("want channel %q, have %q", want, have)
+	}
+}
+
+func TestClientBadToken(t *testing.T) {
+	t.Parallel()
+	h, srv := newHEC()
+	defer srv.Close()
+
+	var errs []error
+	client := splunk.NewClient(srv.URL, "wrong",
+		splunk.Retry(5, time.Millisecond, time.Millisecond),
+		splunk.ErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	client.Log("msg", "x")
+	client.Close()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "401") {
+		t.Errorf("want one 401 error, have %v", errs)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.events) != 0 {
+		t.Errorf("want no events, have %v", h.events)
+	}
+}
+
+func TestClientQueueBound(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"text":"Success","code":0}`)
+	}))
+	defer srv.Close()
+
+	client := splunk.NewClient(srv.URL, "secret", splunk.BatchSize(1), splunk.QueueSize(2))
+	for i := 0; i < 10; i++ {
+		client.Log("i", i)
+	}
+	if client.Dropped() == 0 {
+		t.Error("want dropped log events")
+	}
+	close(release)
+	client.Close()
+}
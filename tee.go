@@ -0,0 +1,105 @@
+package log
+
+import (
+	"strings"
+	"sync"
+)
+
+// Tee is a Logger that passes each log event to several Loggers. The zero
+// value for a Tee discards all log events without error. A Tee must not be
+// modified after it is first used.
+type Tee struct {
+	// Loggers receive each log event in order, or all at once if Concurrent
+	// is set.
+	Loggers []Logger
+
+	// Concurrent makes Log call each Logger in its own goroutine and wait
+	// for all of them to return.
+	Concurrent bool
+
+	// StopOnError makes Log return after the first Logger that returns an
+	// error, without passing the log event to the Loggers after it. It has
+	// no effect if Concurrent is set.
+	StopOnError bool
+}
+
+// NewTee returns a Tee that passes each log event to all of loggers in
+// order, continuing past any that return an error.
+func NewTee(loggers ...Logger) *Tee {
+	return &Tee{Loggers: loggers}
+}
+
+// Log passes a copy of keyvals to each Logger, so that no Logger can observe
+// changes another makes. If any Logger returns an error Log returns a
+// *TeeError holding all of them.
+func (t *Tee) Log(keyvals ...interface{}) error {
+	if t.Concurrent {
+		return t.logConcurrent(keyvals)
+	}
+	var errs []error
+	for _, logger := range t.Loggers {
+		if err := logger.Log(copyKeyvals(keyvals)...); err != nil {
+			errs = append(errs, err)
+			if t.StopOnError {
+				break
+			}
+		}
+	}
+	return newTeeError(errs)
+}
+
+func (t *Tee) logConcurrent(keyvals []interface{}) error {
+	errs := make([]error, len(t.Loggers))
+	var wg sync.WaitGroup
+	wg.Add(len(t.Loggers))
+	for i, logger := range t.Loggers {
+		go func(i int, logger Logger, kvs []interface{}) {
+			defer wg.Done()
+			errs[i] = logger.Log(kvs...)
+		}(i, logger, copyKeyvals(keyvals))
+	}
+	wg.Wait()
+
+	n := 0
+	for _, err := range errs {
+		if err != nil {
+			errs[n] = err
+			n++
+		}
+	}
+	return newTeeError(errs[:n])
+}
+
+func copyKeyvals(keyvals []interface{}) []interface{} {
+	kvs := make([]interface{}, len(keyvals))
+	copy(kvs, keyvals)
+	return kvs
+}
+
+// TeeError is returned by Tee.Log when one or more of its Loggers return an
+// error.
+type TeeError struct {
+	// Errs holds the errors in the order of the Loggers that returned them.
+	Errs []error
+}
+
+func newTeeError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &TeeError{Errs: errs}
+}
+
+func (e *TeeError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors returned by the Loggers, for use with errors.Is
+// and errors.As.
+func (e *TeeError) Unwrap() []error {
+	return e.Errs
+}
@@ -0,0 +1,103 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestTee(t *testing.T) {
+	t.Parallel()
+	for _, concurrent := range []bool{false, true} {
+		logfmt, json := &bytes.Buffer{}, &bytes.Buffer{}
+		tee := log.NewTee(log.NewLogfmtLogger(logfmt), log.NewJSONLogger(json))
+		tee.Concurrent = concurrent
+
+		if err := tee.Log("k", "v"); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "k=v\n", logfmt.String(); want != have {
+			t.Errorf("concurrent=%v: want %#v, have %#v", concurrent, want, have)
+		}
+		if want, have := `{"k":"v"}`+"\n", json.String(); want != have {
+			t.Errorf("concurrent=%v: want %#v, have %#v", concurrent, want, have)
+		}
+	}
+}
+
+func TestTeeCopiesKeyvals(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	mutator := log.LoggerFunc(func(keyvals ...interface{}) error {
+		keyvals[1] = "mutated"
+		return nil
+	})
+	tee := log.NewTee(mutator, log.NewLogfmtLogger(buf))
+
+	kvs := []interface{}{"k", "v"}
+	tee.Log(kvs...)
+	if want, have := "k=v\n", buf.String(); want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+	if want, have := "v", kvs[1]; want != have {
+		t.Errorf("want %#v, have %#v", want, have)
+	}
+}
+
+func TestTeeErrors(t *testing.T) {
+	t.Parallel()
+	err1, err2 := errors.New("first"), errors.New("second")
+	var calls int
+	fail := func(err error) log.Logger {
+		return log.LoggerFunc(func(...interface{}) error { calls++; return err })
+	}
+
+	tee := log.NewTee(fail(err1), fail(nil), fail(err2))
+	err := tee.Log("k", "v")
+	if want, have := 3, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+	teeErr, ok := err.(*log.TeeError)
+	if !ok {
+		t.Fatalf("want *log.TeeError, have %T", err)
+	}
+	if want, have := []error{err1, err2}, teeErr.Unwrap(); len(want) != len(have) || want[0] != have[0] || want[1] != have[1] {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := "first; second", err.Error(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	calls = 0
+	tee.StopOnError = true
+	err = tee.Log("k", "v")
+	if want, have := 1, calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+	if want, have := []error{err1}, err.(*log.TeeError).Unwrap(); len(have) != 1 || want[0] != have[0] {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestTeeZeroValue(t *testing.T) {
+	t.Parallel()
+	var tee log.Tee
+	if err := tee.Log("k", "v"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTeeConcurrency(t *testing.T) {
+	t.Parallel()
+	tee := log.NewTee(log.NewLogfmtLogger(ioutil.Discard), log.NewJSONLogger(ioutil.Discard))
+	tee.Concurrent = true
+	testConcurrency(t, tee, 10000)
+}
+
+func BenchmarkTeeSimple(b *testing.B) {
+	tee := log.NewTee(log.NewLogfmtLogger(ioutil.Discard), log.NewLogfmtLogger(ioutil.Discard))
+	benchmarkRunner(b, tee, baseMessage)
+}
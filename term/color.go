@@ -0,0 +1,235 @@
+package term
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The kind of a Color is stored in its top byte. ANSI colors have kind zero
+// so that the constants keep their values.
+const (
+	colorKindMask    = 0xff << 24
+	colorKindPalette = 1 << 24
+	colorKindRGB     = 2 << 24
+)
+
+// Palette returns color n of the 256 color xterm palette. Colors 0 to 15 are
+// the ANSI colors, 16 to 231 form a 6x6x6 color cube and 232 to 255 a
+// grayscale ramp.
+func Palette(n uint8) Color {
+	return Color(colorKindPalette | uint32(n))
+}
+
+// RGB returns the 24-bit color with the red, green and blue components r, g
+// and b.
+func RGB(r, g, b uint8) Color {
+	return Color(colorKindRGB | uint32(r)<<16 | uint32(g)<<8 | uint32(b))
+}
+
+// Attr is a set of text attributes.
+type Attr uint8
+
+// Text attributes. Not all terminals show them all.
+const (
+	Bold Attr = 1 << iota
+	Italic
+	Underline
+)
+
+// ColorProfile describes the colors a terminal can show.
+type ColorProfile uint8
+
+// Color profiles, each of which supports the colors of the ones before it.
+const (
+	// ANSI terminals show the 16 ANSI colors.
+	ANSI ColorProfile = iota
+
+	// ANSI256 terminals show the 256 colors of the xterm palette.
+	ANSI256
+
+	// TrueColor terminals show 24-bit RGB colors.
+	TrueColor
+)
+
+// DetectColorProfile returns the color profile of the terminal as described
+// by the COLORTERM and TERM environment variables. It returns TrueColor if
+// COLORTERM is "truecolor" or "24bit" or TERM ends in "-direct", ANSI256 if
+// TERM contains "256color", and ANSI otherwise. A FORCE_COLOR value of "2"
+// or "3" selects ANSI256 or TrueColor regardless.
+func DetectColorProfile() ColorProfile {
+	switch os.Getenv("FORCE_COLOR") {
+	case "2":
+		return ANSI256
+	case "3":
+		return TrueColor
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return TrueColor
+	}
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case strings.HasSuffix(term, "-direct"):
+		return TrueColor
+	case strings.Contains(term, "256color"):
+		return ANSI256
+	}
+	return ANSI
+}
+
+// Convert returns the color nearest to c that terminals with profile p can
+// show. Palette colors 0 to 15 are always converted to the equivalent ANSI
+// color.
+func (c Color) Convert(p ColorProfile) Color {
+	switch c & colorKindMask {
+	case colorKindPalette:
+		n := uint8(c)
+		if n < 16 {
+			return Black + Color(n)
+		}
+		if p >= ANSI256 {
+			return c
+		}
+		return nearestANSI(paletteRGB(n))
+	case colorKindRGB:
+		r, g, b := uint8(c>>16), uint8(c>>8), uint8(c)
+		switch p {
+		case TrueColor:
+			return c
+		case ANSI256:
+			return nearestPalette(r, g, b)
+		}
+		return nearestANSI(r, g, b)
+	}
+	return c
+}
+
+// ansiRGB holds the RGB values xterm uses for the ANSI colors, in palette
+// order.
+var ansiRGB = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels holds the component values of the palette color cube.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// paletteRGB returns the RGB value of palette color n.
+func paletteRGB(n uint8) (r, g, b uint8) {
+	switch {
+	case n < 16:
+		c := ansiRGB[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		i := n - 16
+		return cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6]
+	}
+	v := 8 + 10*(n-232)
+	return v, v, v
+}
+
+// nearestPalette returns the palette color nearest to r, g, b from the color
+// cube or the grayscale ramp. The ANSI colors are not considered since their
+// values differ between terminals.
+func nearestPalette(r, g, b uint8) Color {
+	cube := 16 + 36*cubeIndex(r) + 6*cubeIndex(g) + cubeIndex(b)
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	gray := 232
+	if avg > 8 {
+		gray += (avg - 3) / 10
+		if gray > 255 {
+			gray = 255
+		}
+	}
+
+	cr, cg, cb := paletteRGB(uint8(cube))
+	gr, gg, gb := paletteRGB(uint8(gray))
+	if distance(r, g, b, gr, gg, gb) < distance(r, g, b, cr, cg, cb) {
+		return Palette(uint8(gray))
+	}
+	return Palette(uint8(cube))
+}
+
+// cubeIndex returns the index in cubeLevels nearest to v.
+func cubeIndex(v uint8) int {
+	switch {
+	case v < 48:
+		return 0
+	case v < 115:
+		return 1
+	}
+	return (int(v) - 35) / 40
+}
+
+// nearestANSI returns the ANSI color nearest to r, g, b.
+func nearestANSI(r, g, b uint8) Color {
+	best, bestDist := 0, -1
+	for i, c := range ansiRGB {
+		if d := distance(r, g, b, c[0], c[1], c[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return Black + Color(best)
+}
+
+// distance returns the squared Euclidean distance between two RGB values.
+func distance(r1, g1, b1, r2, g2, b2 uint8) int {
+	dr, dg, db := int(r1)-int(r2), int(g1)-int(g2), int(b1)-int(b2)
+	return dr*dr + dg*dg + db*db
+}
+
+var resetAttrBytes = []byte("\x1b[23;24m")
+
+// writeStart writes the ANSI codes that switch the terminal to c, converted
+// to profile p.
+func (c FgBgColor) writeStart(buf *bytes.Buffer, p ColorProfile) {
+	if c.Attr != 0 {
+		buf.WriteString("\x1b[")
+		sep := ""
+		for _, a := range []struct {
+			attr Attr
+			code string
+		}{{Bold, "1"}, {Italic, "3"}, {Underline, "4"}} {
+			if c.Attr&a.attr != 0 {
+				buf.WriteString(sep)
+				buf.WriteString(a.code)
+				sep = ";"
+			}
+		}
+		buf.WriteByte('m')
+	}
+	writeColorCode(buf, c.Fg.Convert(p), fgColorBytes, "38")
+	writeColorCode(buf, c.Bg.Convert(p), bgColorBytes, "48")
+}
+
+// writeEnd writes the ANSI codes that undo writeStart.
+func (c FgBgColor) writeEnd(buf *bytes.Buffer) {
+	buf.Write(resetColorBytes)
+	if c.Attr&(Italic|Underline) != 0 {
+		buf.Write(resetAttrBytes)
+	}
+}
+
+// writeColorCode writes the code selecting color c, using ansi for the ANSI
+// colors and the extended color code ext, 38 for the foreground or 48 for
+// the background, for the others.
+func writeColorCode(buf *bytes.Buffer, c Color, ansi [][]byte, ext string) {
+	switch c & colorKindMask {
+	case 0:
+		if c != Default {
+			buf.Write(ansi[c])
+		}
+	case colorKindPalette:
+		buf.WriteString("\x1b[" + ext + ";5;" + strconv.Itoa(int(uint8(c))) + "m")
+	case colorKindRGB:
+		buf.WriteString("\x1b[" + ext + ";2;" +
+			strconv.Itoa(int(uint8(c>>16))) + ";" +
+			strconv.Itoa(int(uint8(c>>8))) + ";" +
+			strconv.Itoa(int(uint8(c))) + "m")
+	}
+}
@@ -0,0 +1,106 @@
+package term_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/term"
+)
+
+func TestColorConvert(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		color   term.Color
+		profile term.ColorProfile
+		want    term.Color
+	}{
+		{term.Default, term.ANSI, term.Default},
+		{term.Yellow, term.ANSI, term.Yellow},
+		{term.Palette(9), term.TrueColor, term.Red},
+		{term.Palette(196), term.TrueColor, term.Palette(196)},
+		{term.Palette(196), term.ANSI256, term.Palette(196)},
+		{term.Palette(196), term.ANSI, term.Red},
+		{term.Palette(244), term.ANSI, term.DarkGray},
+		{term.RGB(1, 2, 3), term.TrueColor, term.RGB(1, 2, 3)},
+		{term.RGB(255, 0, 0), term.ANSI256, term.Palette(196)},
+		{term.RGB(128, 128, 128), term.ANSI256, term.Palette(244)},
+		{term.RGB(255, 255, 255), term.ANSI256, term.Palette(231)},
+		{term.RGB(0, 0, 0), term.ANSI, term.Black},
+		{term.RGB(250, 250, 250), term.ANSI, term.White},
+		{term.RGB(0, 0, 200), term.ANSI, term.DarkBlue},
+	}
+	for _, test := range tests {
+		if want, have := test.want, test.color.Convert(test.profile); want != have {
+			t.Errorf("%#x.Convert(%d): want %#x, have %#x", test.color, test.profile, want, have)
+		}
+	}
+}
+
+func TestDetectColorProfile(t *testing.T) {
+	tests := []struct {
+		colorterm, term string
+		want            term.ColorProfile
+	}{
+		{"truecolor", "xterm", term.TrueColor},
+		{"24BIT", "", term.TrueColor},
+		{"", "xterm-direct", term.TrueColor},
+		{"", "xterm-256color", term.ANSI256},
+		{"", "screen-256color", term.ANSI256},
+		{"", "xterm", term.ANSI},
+		{"", "", term.ANSI},
+	}
+	for _, test := range tests {
+		t.Setenv("FORCE_COLOR", "")
+		t.Setenv("COLORTERM", test.colorterm)
+		t.Setenv("TERM", test.term)
+		if want, have := test.want, term.DetectColorProfile(); want != have {
+			t.Errorf("COLORTERM=%q TERM=%q: want %d, have %d", test.colorterm, test.term, want, have)
+		}
+	}
+}
+
+func TestHighlightLoggerExtendedColors(t *testing.T) {
+	h := term.Highlight{
+		Key:   term.FgBgColor{Fg: term.RGB(1, 2, 3), Attr: term.Bold | term.Underline},
+		Value: term.FgBgColor{Fg: term.Palette(208), Bg: term.Palette(17)},
+	}
+	tests := []struct {
+		colorterm, term string
+		want            string
+	}{
+		{
+			colorterm: "truecolor",
+			want:      "\x1b[1;4m\x1b[38;2;1;2;3ma\x1b[39;49;22m\x1b[23;24m=\x1b[38;5;208m\x1b[48;5;17m1\x1b[39;49;22m\n",
+		},
+		{
+			term: "xterm",
+			want: "\x1b[1;4m\x1b[30ma\x1b[39;49;22m\x1b[23;24m=\x1b[33m\x1b[40m1\x1b[39;49;22m\n",
+		},
+	}
+	for _, test := range tests {
+		t.Setenv("FORCE_COLOR", "")
+		t.Setenv("COLORTERM", test.colorterm)
+		t.Setenv("TERM", test.term)
+		var buf bytes.Buffer
+		if err := term.NewHighlightLogger(&buf, h).Log("a", 1); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := test.want, buf.String(); want != have {
+			t.Errorf("COLORTERM=%q TERM=%q:\nwant %q\nhave %q", test.colorterm, test.term, want, have)
+		}
+	}
+}
+
+func TestColorLoggerAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := term.NewColorLogger(&buf, log.NewLogfmtLogger, func(keyvals ...interface{}) term.FgBgColor {
+		return term.FgBgColor{Attr: term.Italic}
+	})
+	if err := logger.Log("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "\x1b[3ma=1\n\x1b[39;49;22m\x1b[23;24m", buf.String(); want != have {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"github.com/go-kit/log"
 )
 
-// Color represents an ANSI color. The zero value is Default.
-type Color uint8
+// Color represents a terminal color: one of the ANSI colors below, a color
+// of the 256 color palette returned by Palette, or a 24-bit color returned
+// by RGB. The zero value is Default.
+type Color uint32
 
 // ANSI colors.
 const (
@@ -65,19 +67,27 @@ func init() {
 	}
 }
 
-// FgBgColor represents a foreground and background color.
+// FgBgColor represents a foreground and background color and text
+// attributes.
 type FgBgColor struct {
 	Fg, Bg Color
+	Attr   Attr
 }
 
 func (c FgBgColor) isZero() bool {
-	return c.Fg == Default && c.Bg == Default
+	return c.Fg == Default && c.Bg == Default && c.Attr == 0
 }
 
 // NewColorLogger returns a Logger which writes colored logs to w. ANSI color
 // codes for the colors returned by color are added to the formatted output
 // from the Logger returned by newLogger and the combined result written to w.
+// Colors the terminal cannot show, according to DetectColorProfile, are
+// replaced with the nearest ones it can.
 func NewColorLogger(w io.Writer, newLogger func(io.Writer) log.Logger, color func(keyvals ...interface{}) FgBgColor) log.Logger {
+	return newColorLogger(w, newLogger, color, DetectColorProfile())
+}
+
+func newColorLogger(w io.Writer, newLogger func(io.Writer) log.Logger, color func(keyvals ...interface{}) FgBgColor, profile ColorProfile) log.Logger {
 	if color == nil {
 		panic("color func nil")
 	}
@@ -87,6 +97,7 @@ func NewColorLogger(w io.Writer, newLogger func(io.Writer) log.Logger, color fun
 		color:         color,
 		bufPool:       sync.Pool{New: func() interface{} { return &loggerBuf{} }},
 		noColorLogger: newLogger(w),
+		profile:       profile,
 	}
 }
 
@@ -96,6 +107,7 @@ type colorLogger struct {
 	color         func(keyvals ...interface{}) FgBgColor
 	bufPool       sync.Pool
 	noColorLogger log.Logger
+	profile       ColorProfile
 }
 
 func (l *colorLogger) Log(keyvals ...interface{}) error {
@@ -106,19 +118,12 @@ func (l *colorLogger) Log(keyvals ...interface{}) error {
 
 	lb := l.getLoggerBuf()
 	defer l.putLoggerBuf(lb)
-	if color.Fg != Default {
-		lb.buf.Write(fgColorBytes[color.Fg])
-	}
-	if color.Bg != Default {
-		lb.buf.Write(bgColorBytes[color.Bg])
-	}
+	color.writeStart(lb.buf, l.profile)
 	err := lb.logger.Log(keyvals...)
 	if err != nil {
 		return err
 	}
-	if color.Fg != Default || color.Bg != Default {
-		lb.buf.Write(resetColorBytes)
-	}
+	color.writeEnd(lb.buf)
 	_, err = io.Copy(l.w, lb.buf)
 	return err
 }
@@ -96,9 +96,21 @@ loop:
 			}
 			token := strings.Split(cs, ";")
 			intensityMode := word(0)
-			for _, ns := range token {
-				if n, err = strconv.Atoi(ns); err == nil {
+			for i := 0; i < len(token); i++ {
+				if n, err = strconv.Atoi(token[i]); err == nil {
 					switch {
+					case n == 38 || n == 48:
+						// The console cannot show 256 color palette or RGB
+						// colors, so skip their arguments.
+						if i+1 < len(token) {
+							switch token[i+1] {
+							case "5":
+								i += 2
+							case "2":
+								i += 4
+							}
+						}
+						continue
 					case n == 0:
 						attr = w.oldattr
 					case n == 1:
@@ -0,0 +1,215 @@
+package term
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/internal/fields"
+	"github.com/go-kit/log/level"
+)
+
+// DefaultConsoleTimeFormat is the layout used by NewConsoleLogger to format
+// timestamps unless ConsoleTimeFormat is given.
+const DefaultConsoleTimeFormat = "15:04:05.000"
+
+// NewConsoleLogger returns a Logger that formats log events for people
+// reading them in a terminal, such as during development, rather than for
+// machines. Each event is written on one line as
+//
+//	15:04:05.000 INF  component message  key=value ...
+//
+// The timestamp is taken from the "ts" value if it is a time, such as one
+// produced by log.DefaultTimestamp, and is the current time otherwise. The
+// level is abbreviated to a fixed width token. The values of the "component"
+// and "msg" keys follow, and the remaining key/value pairs are written in
+// logfmt format.
+//
+// If UseColor reports that output to w should be colored, the timestamp,
+// level and component are colored, as are the message and key/value pairs if
+// ConsoleHighlight is given. Each log event produces no more than one call to
+// w.Write.
+func NewConsoleLogger(w io.Writer, options ...ConsoleOption) log.Logger {
+	l := &consoleLogger{
+		w:            w,
+		timeFormat:   DefaultConsoleTimeFormat,
+		componentKey: "component",
+		profile:      DetectColorProfile(),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	l.color = UseColor(w, l.mode)
+	if l.color {
+		l.w = NewColorWriter(w)
+	}
+	return l
+}
+
+// ConsoleOption sets a parameter for the Logger returned by
+// NewConsoleLogger.
+type ConsoleOption func(*consoleLogger)
+
+// ConsoleTimeFormat sets the layout, as understood by time.Time.Format, of
+// the timestamp that starts each line. The default is
+// DefaultConsoleTimeFormat.
+func ConsoleTimeFormat(layout string) ConsoleOption {
+	return func(l *consoleLogger) { l.timeFormat = layout }
+}
+
+// ConsoleComponentKey sets the key whose value is written before the
+// message. The default is "component"; an empty key disables it.
+func ConsoleComponentKey(key string) ConsoleOption {
+	return func(l *consoleLogger) { l.componentKey = key }
+}
+
+// ConsoleHighlight sets the colors of the message and of the key/value pairs
+// that follow it. The Key, Value and Message colors and the ValueColor
+// function of h are used; the level is colored separately.
+func ConsoleHighlight(h Highlight) ConsoleOption {
+	return func(l *consoleLogger) { l.highlight = h }
+}
+
+// ConsoleTheme sets the colors of the level token. By default debug, info,
+// warn and error are colored blue, green, yellow and red.
+func ConsoleTheme(t Theme) ConsoleOption {
+	return func(l *consoleLogger) { l.theme = t }
+}
+
+// ConsoleColors sets when the output is colored. The default is ColorAuto.
+func ConsoleColors(mode ColorMode) ConsoleOption {
+	return func(l *consoleLogger) { l.mode = mode }
+}
+
+type consoleLogger struct {
+	w            io.Writer
+	mode         ColorMode
+	color        bool
+	timeFormat   string
+	componentKey string
+	highlight    Highlight
+	theme        Theme
+	profile      ColorProfile
+}
+
+// consoleLevelWidth is the width of the level column.
+const consoleLevelWidth = 3
+
+var (
+	consoleTimeColor      = FgBgColor{Fg: DarkGray}
+	consoleComponentColor = FgBgColor{Fg: DarkCyan}
+
+	consoleLevels = map[fields.Level]struct {
+		token string
+		color FgBgColor
+	}{
+		fields.LevelTrace:     {"TRC", FgBgColor{Fg: DarkGray}},
+		fields.LevelDebug:     {"DBG", FgBgColor{Fg: Blue}},
+		fields.LevelInfo:      {"INF", FgBgColor{Fg: Green}},
+		fields.LevelNotice:    {"NTC", FgBgColor{Fg: Cyan}},
+		fields.LevelWarn:      {"WRN", FgBgColor{Fg: Yellow}},
+		fields.LevelError:     {"ERR", FgBgColor{Fg: Red}},
+		fields.LevelCritical:  {"CRT", FgBgColor{Fg: White, Bg: DarkRed}},
+		fields.LevelAlert:     {"ALT", FgBgColor{Fg: White, Bg: DarkRed}},
+		fields.LevelFatal:     {"FTL", FgBgColor{Fg: White, Bg: DarkRed}},
+		fields.LevelEmergency: {"EMG", FgBgColor{Fg: White, Bg: DarkRed}},
+	}
+)
+
+func (l *consoleLogger) Log(keyvals ...interface{}) error {
+	var (
+		ts                         time.Time
+		lvl, msg, component        interface{}
+		haveTS, haveLevel, haveMsg bool
+		haveComp                   bool
+		rest                       = make([]interface{}, 0, len(keyvals))
+	)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		switch {
+		case !haveTS && k == "ts":
+			if t, ok := fields.Time(v); ok {
+				ts, haveTS = t, true
+				continue
+			}
+		case !haveLevel && k == level.Key():
+			lvl, haveLevel = v, true
+			continue
+		case !haveMsg && k == "msg":
+			msg, haveMsg = v, true
+			continue
+		case !haveComp && l.componentKey != "" && k == l.componentKey:
+			component, haveComp = v, true
+			continue
+		}
+		rest = append(rest, k, v)
+	}
+	if !haveTS {
+		ts = time.Now()
+	}
+	var levelToken string
+	var levelColor FgBgColor
+	if haveLevel {
+		levelToken, levelColor = consoleLevel(lvl)
+		if l.theme != nil {
+			levelColor = l.theme.Level(lvl)
+		}
+	}
+
+	timeColor, componentColor, h := consoleTimeColor, consoleComponentColor, l.highlight
+	if !l.color {
+		timeColor, componentColor, levelColor, h = FgBgColor{}, FgBgColor{}, FgBgColor{}, Highlight{}
+	}
+
+	hb := getHighlightBuf()
+	defer highlightBufPool.Put(hb)
+
+	writeColored(&hb.buf, timeColor, l.profile, []byte(ts.Format(l.timeFormat)))
+	hb.buf.WriteByte(' ')
+	writeColored(&hb.buf, levelColor, l.profile, []byte(levelToken))
+	for n := utf8.RuneCountInString(levelToken); n < consoleLevelWidth; n++ {
+		hb.buf.WriteByte(' ')
+	}
+	sep := "  "
+	if haveComp {
+		hb.buf.WriteString(sep)
+		writeColored(&hb.buf, componentColor, l.profile, []byte(fmt.Sprint(component)))
+		sep = " "
+	}
+	if haveMsg {
+		hb.buf.WriteString(sep)
+		writeColored(&hb.buf, h.valueColor("msg", msg), l.profile, []byte(fmt.Sprint(msg)))
+	}
+	if len(rest) > 0 {
+		hb.buf.WriteString("  ")
+		if err := hb.appendPairs(&h, l.profile, rest); err != nil {
+			return err
+		}
+	}
+	hb.buf.WriteByte('\n')
+
+	_, err := l.w.Write(hb.buf.Bytes())
+	return err
+}
+
+// consoleLevel returns the token and color for the level value v. Levels
+// without a standard abbreviation are shortened to their first letters.
+func consoleLevel(v interface{}) (string, FgBgColor) {
+	name := fmt.Sprint(v)
+	if lvl, ok := fields.ParseLevel(name); ok {
+		l := consoleLevels[lvl]
+		return l.token, l.color
+	}
+	token := []rune(strings.ToUpper(name))
+	if len(token) > consoleLevelWidth {
+		token = token[:consoleLevelWidth]
+	}
+	return string(token), FgBgColor{}
+}
@@ -0,0 +1,133 @@
+package term_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-kit/log/term"
+)
+
+var (
+	consoleTime  = time.Date(2023, 5, 17, 14, 3, 27, 512e6, time.UTC)
+	clockPattern = regexp.MustCompile(`^\d\d:\d\d:\d\d\.\d{3}`)
+)
+
+func TestConsoleLogger(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		keyvals []interface{}
+		want    string
+	}{
+		{
+			keyvals: []interface{}{"ts", consoleTime, "level", level.InfoValue(), "component", "http", "msg", "listening", "addr", ":8080"},
+			want:    "14:03:27.512 INF  http listening  addr=:8080\n",
+		},
+		{
+			keyvals: []interface{}{"ts", consoleTime, "msg", "no level"},
+			want:    "14:03:27.512      no level\n",
+		},
+		{
+			keyvals: []interface{}{"ts", consoleTime, "level", "error", "err", errors.New("boom"), "msg", "failed"},
+			want:    "14:03:27.512 ERR  failed  err=boom\n",
+		},
+		{
+			keyvals: []interface{}{"ts", consoleTime, "level", "Warning", "a", 1, "b"},
+			want:    "14:03:27.512 WRN  a=1 b=(MISSING)\n",
+		},
+		{
+			keyvals: []interface{}{"ts", consoleTime, "level", "audit", "msg", "login"},
+			want:    "14:03:27.512 AUD  login\n",
+		},
+		{
+			keyvals: []interface{}{"ts", consoleTime, "level", "ünïcode", "msg", "m"},
+			want:    "14:03:27.512 ÜNÏ  m\n",
+		},
+		{
+			keyvals: []interface{}{"ts", consoleTime, "level", "é", "msg", "m"},
+			want:    "14:03:27.512 É    m\n",
+		},
+		{
+			keyvals: []interface{}{"ts", "not a time", "msg", "m", "msg", "again"},
+			want:    "??:??:??.???      m  ts=\"not a time\" msg=again\n",
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		logger := term.NewConsoleLogger(&buf, term.ConsoleColors(term.ColorNever))
+		if err := logger.Log(test.keyvals...); err != nil {
+			t.Fatal(err)
+		}
+		have := buf.String()
+		if strings.HasPrefix(test.want, "??") {
+			// The current time replaces a missing timestamp.
+			have = clockPattern.ReplaceAllString(have, "??:??:??.???")
+		}
+		if want := test.want; want != have {
+			t.Errorf("\nwant %q\nhave %q", want, have)
+		}
+	}
+}
+
+func TestConsoleLoggerTimestampValuer(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := term.NewConsoleLogger(&buf, term.ConsoleColors(term.ColorNever), term.ConsoleTimeFormat(time.Kitchen), term.ConsoleComponentKey("module"))
+	logger = log.With(logger, "ts", log.TimestampFormat(func() time.Time { return consoleTime }, time.RFC3339), "module", "db")
+	if err := level.Warn(logger).Log("msg", "slow query"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "2:03PM WRN  db slow query\n", buf.String(); want != have {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+}
+
+func TestConsoleLoggerColors(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := term.NewConsoleLogger(&buf, term.ConsoleColors(term.ColorAlways))
+	if err := logger.Log("ts", consoleTime, "level", "warn", "component", "db", "msg", "slow", "ms", 250); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[30;1m14:03:27.512\x1b[39;49;22m \x1b[33;1mWRN\x1b[39;49;22m  \x1b[36mdb\x1b[39;49;22m slow  ms=250\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+}
+
+func TestConsoleLoggerHighlight(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := term.NewConsoleLogger(&buf, term.ConsoleColors(term.ColorAlways), term.ConsoleHighlight(testHighlight))
+	if err := logger.Log("ts", consoleTime, "msg", "failed", "err", errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[30;1m14:03:27.512\x1b[39;49;22m      \x1b[37;1mfailed\x1b[39;49;22m  \x1b[36merr\x1b[39;49;22m=\x1b[31;1mboom\x1b[39;49;22m\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+
+	// Without colors the highlight is ignored.
+	buf.Reset()
+	logger = term.NewConsoleLogger(&buf, term.ConsoleColors(term.ColorNever), term.ConsoleHighlight(testHighlight))
+	if err := logger.Log("ts", consoleTime, "msg", "failed", "err", errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "14:03:27.512      failed  err=boom\n", buf.String(); want != have {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+}
+
+func BenchmarkConsoleLoggerSimple(b *testing.B) {
+	benchmarkRunner(b, term.NewConsoleLogger(ioutil.Discard), baseMessage)
+}
+
+func TestConsoleLoggerConcurrency(t *testing.T) {
+	testConcurrency(t, term.NewConsoleLogger(ioutil.Discard))
+}
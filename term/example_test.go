@@ -2,9 +2,11 @@ package term_test
 
 import (
 	"errors"
+	"fmt"
 	"os"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/go-kit/log/term"
 )
 
@@ -55,3 +57,18 @@ func ExampleNewLogger_levelColors() {
 	logger.Log("level", "warn", "msg", "yellow")
 	logger.Log("level", "debug", "msg", "dark gray")
 }
+
+func ExampleTheme() {
+	// Color by level, with overrides such as "info=green;trace=:darkblue"
+	// taken from the APP_LOG_COLORS environment variable.
+	theme, err := term.LoadTheme("APP_LOG_COLORS")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	logger := term.NewLogger(os.Stdout, log.NewLogfmtLogger, theme.Color)
+
+	level.Warn(logger).Log("msg", "yellow")
+	level.Debug(logger).Log("msg", "dark gray")
+	logger.Log("level", "trace", "msg", "dark blue background")
+}
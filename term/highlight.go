@@ -0,0 +1,149 @@
+package term
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// Highlight describes how the parts of a logfmt formatted log event are
+// colored by NewHighlightLogger. Zero colors leave the corresponding text
+// uncolored.
+type Highlight struct {
+	// Key colors each key.
+	Key FgBgColor
+
+	// Value colors each value not colored by one of the fields below.
+	Value FgBgColor
+
+	// Level colors the value of the level.Key() pair.
+	Level FgBgColor
+
+	// Message colors the value of the "msg" pair.
+	Message FgBgColor
+
+	// ValueColor, if not nil, is called with each key/value pair. A non-zero
+	// result takes priority over all of the colors above.
+	ValueColor func(key, value interface{}) FgBgColor
+}
+
+// ErrorValueColor returns a function for Highlight.ValueColor that colors
+// non-nil error values c.
+func ErrorValueColor(c FgBgColor) func(key, value interface{}) FgBgColor {
+	return func(_, value interface{}) FgBgColor {
+		if _, ok := value.(error); ok {
+			return c
+		}
+		return FgBgColor{}
+	}
+}
+
+// valueColor returns the color of value.
+func (h *Highlight) valueColor(key, value interface{}) FgBgColor {
+	if h.ValueColor != nil {
+		if c := h.ValueColor(key, value); !c.isZero() {
+			return c
+		}
+	}
+	switch {
+	case key == level.Key() && !h.Level.isZero():
+		return h.Level
+	case key == "msg" && !h.Message.isZero():
+		return h.Message
+	}
+	return h.Value
+}
+
+// NewHighlightLogger returns a Logger that writes log events to w in logfmt
+// format, coloring keys and values according to h. Unlike NewColorLogger,
+// which colors whole lines, it makes the parts of each line easy to tell
+// apart. Colors the terminal cannot show, according to DetectColorProfile,
+// are replaced with the nearest ones it can. Each log event produces no more
+// than one call to w.Write.
+func NewHighlightLogger(w io.Writer, h Highlight) log.Logger {
+	return &highlightLogger{w: w, h: h, profile: DetectColorProfile()}
+}
+
+type highlightLogger struct {
+	w       io.Writer
+	h       Highlight
+	profile ColorProfile
+}
+
+func (l *highlightLogger) Log(keyvals ...interface{}) error {
+	hb := getHighlightBuf()
+	defer highlightBufPool.Put(hb)
+	if err := hb.appendPairs(&l.h, l.profile, keyvals); err != nil {
+		return err
+	}
+	hb.buf.WriteByte('\n')
+	_, err := l.w.Write(hb.buf.Bytes())
+	return err
+}
+
+// highlightBuf formats key/value pairs one at a time with a logfmt Logger so
+// that the key and value can be colored separately.
+type highlightBuf struct {
+	buf    bytes.Buffer
+	pair   bytes.Buffer
+	logfmt log.Logger
+}
+
+var highlightBufPool = sync.Pool{
+	New: func() interface{} {
+		hb := &highlightBuf{}
+		hb.logfmt = log.NewLogfmtLogger(&hb.pair)
+		return hb
+	},
+}
+
+func getHighlightBuf() *highlightBuf {
+	hb := highlightBufPool.Get().(*highlightBuf)
+	hb.buf.Reset()
+	return hb
+}
+
+// appendPairs writes keyvals to hb.buf in logfmt format, highlighted by h in
+// profile p, separating pairs with spaces.
+func (hb *highlightBuf) appendPairs(h *Highlight, p ColorProfile, keyvals []interface{}) error {
+	first := true
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{}
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		hb.pair.Reset()
+		if err := hb.logfmt.Log(k, v); err != nil {
+			return err
+		}
+		pair := bytes.TrimSuffix(hb.pair.Bytes(), []byte("\n"))
+		eq := bytes.IndexByte(pair, '=')
+		if eq < 0 {
+			// The pair was skipped.
+			continue
+		}
+		if !first {
+			hb.buf.WriteByte(' ')
+		}
+		first = false
+		writeColored(&hb.buf, h.Key, p, pair[:eq])
+		hb.buf.WriteByte('=')
+		writeColored(&hb.buf, h.valueColor(k, v), p, pair[eq+1:])
+	}
+	return nil
+}
+
+// writeColored writes b to buf in color c, converted to profile p.
+func writeColored(buf *bytes.Buffer, c FgBgColor, p ColorProfile, b []byte) {
+	if c.isZero() {
+		buf.Write(b)
+		return
+	}
+	c.writeStart(buf, p)
+	buf.Write(b)
+	c.writeEnd(buf)
+}
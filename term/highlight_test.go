@@ -0,0 +1,73 @@
+package term_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-kit/log/term"
+)
+
+var testHighlight = term.Highlight{
+	Key:        term.FgBgColor{Fg: term.DarkCyan},
+	Level:      term.FgBgColor{Fg: term.Yellow},
+	Message:    term.FgBgColor{Fg: term.White},
+	ValueColor: term.ErrorValueColor(term.FgBgColor{Fg: term.Red}),
+}
+
+func TestHighlightLogger(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		keyvals []interface{}
+		want    string
+	}{
+		{
+			keyvals: []interface{}{"level", level.WarnValue(), "msg", "slow query", "ms", 250},
+			want:    "\x1b[36mlevel\x1b[39;49;22m=\x1b[33;1mwarn\x1b[39;49;22m \x1b[36mmsg\x1b[39;49;22m=\x1b[37;1m\"slow query\"\x1b[39;49;22m \x1b[36mms\x1b[39;49;22m=250\n",
+		},
+		{
+			keyvals: []interface{}{"err", errors.New("boom"), "err", nil},
+			want:    "\x1b[36merr\x1b[39;49;22m=\x1b[31;1mboom\x1b[39;49;22m \x1b[36merr\x1b[39;49;22m=null\n",
+		},
+		{
+			keyvals: []interface{}{nil, "skipped", "odd"},
+			want:    "\x1b[36modd\x1b[39;49;22m=null\n",
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		logger := term.NewHighlightLogger(&buf, testHighlight)
+		if err := logger.Log(test.keyvals...); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := test.want, buf.String(); want != have {
+			t.Errorf("\nwant %q\nhave %q", want, have)
+		}
+	}
+}
+
+// TestHighlightLoggerPlain checks that without colors the output matches
+// that of a logfmt Logger.
+func TestHighlightLoggerPlain(t *testing.T) {
+	t.Parallel()
+	keyvals := []interface{}{"msg", "hello world", "a", 1, "m", map[string]int{}, "b"}
+	var want, have bytes.Buffer
+	log.NewLogfmtLogger(&want).Log(keyvals...)
+	if err := term.NewHighlightLogger(&have, term.Highlight{}).Log(keyvals...); err != nil {
+		t.Fatal(err)
+	}
+	if want.String() != have.String() {
+		t.Errorf("\nwant %q\nhave %q", want.String(), have.String())
+	}
+}
+
+func BenchmarkHighlightLoggerSimple(b *testing.B) {
+	benchmarkRunner(b, term.NewHighlightLogger(ioutil.Discard, testHighlight), baseMessage)
+}
+
+func TestHighlightLoggerConcurrency(t *testing.T) {
+	testConcurrency(t, term.NewHighlightLogger(ioutil.Discard, testHighlight))
+}
@@ -3,18 +3,121 @@ package term
 
 import (
 	"io"
+	"os"
+	"strings"
 
 	"github.com/go-kit/log"
 )
 
 // NewLogger returns a Logger that takes advantage of terminal features if
 // possible. Log events are formatted by the Logger returned by newLogger. If
-// w is a terminal each log event is colored according to the color function.
-func NewLogger(w io.Writer, newLogger func(io.Writer) log.Logger, color func(keyvals ...interface{}) FgBgColor) log.Logger {
-	if !IsTerminal(w) {
+// UseColor reports that output to w should be colored, each log event is
+// colored according to the color function. Options override those defaults.
+func NewLogger(w io.Writer, newLogger func(io.Writer) log.Logger, color func(keyvals ...interface{}) FgBgColor, options ...Option) log.Logger {
+	var o loggerOptions
+	for _, option := range options {
+		option(&o)
+	}
+	if !UseColor(w, o.mode) {
 		return newLogger(w)
 	}
-	return NewColorLogger(NewColorWriter(w), newLogger, color)
+	if !o.hasProfile {
+		o.profile = DetectColorProfile()
+	}
+	return newColorLogger(NewColorWriter(w), newLogger, color, o.profile)
+}
+
+// Option sets a parameter for the Logger returned by NewLogger.
+type Option func(*loggerOptions)
+
+type loggerOptions struct {
+	mode       ColorMode
+	profile    ColorProfile
+	hasProfile bool
+}
+
+// Colors sets when the output is colored. The default is ColorAuto.
+func Colors(mode ColorMode) Option {
+	return func(o *loggerOptions) { o.mode = mode }
+}
+
+// Profile sets the colors the terminal can show, overriding
+// DetectColorProfile.
+func Profile(p ColorProfile) Option {
+	return func(o *loggerOptions) { o.profile, o.hasProfile = p, true }
+}
+
+// ColorMode controls when output is colored.
+type ColorMode uint8
+
+// Color modes.
+const (
+	// ColorAuto colors output when UseColor says so.
+	ColorAuto ColorMode = iota
+
+	// ColorAlways always colors output.
+	ColorAlways
+
+	// ColorNever never colors output.
+	ColorNever
+)
+
+// UseColor reports whether output to w should be colored in the given mode.
+// In ColorAuto mode it follows common conventions, in order:
+//
+//   - If NO_COLOR is set to a non-empty value, output is not colored.
+//   - If FORCE_COLOR is set to a non-empty value, output is colored unless
+//     the value is "0" or "false".
+//   - If CLICOLOR_FORCE is set to a value other than "0", output is colored.
+//   - If CLICOLOR is "0" or TERM is "dumb", output is not colored.
+//   - If w is a terminal, or the program runs in a CI service whose logs
+//     render ANSI colors, such as GitHub Actions or GitLab CI, output is
+//     colored.
+//
+// Otherwise output is not colored.
+func UseColor(w io.Writer, mode ColorMode) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v := os.Getenv("FORCE_COLOR"); v != "" {
+		return v != "0" && !strings.EqualFold(v, "false")
+	}
+	if v := os.Getenv("CLICOLOR_FORCE"); v != "" && v != "0" {
+		return true
+	}
+	if os.Getenv("CLICOLOR") == "0" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(w) || isColorCI()
+}
+
+// colorCIVars lists environment variables set by CI services whose log
+// viewers render ANSI colors.
+var colorCIVars = []string{
+	"GITHUB_ACTIONS",
+	"GITEA_ACTIONS",
+	"GITLAB_CI",
+	"BUILDKITE",
+	"CIRCLECI",
+	"DRONE",
+	"TRAVIS",
+}
+
+// isColorCI reports whether the program runs in a CI service whose logs
+// render ANSI colors.
+func isColorCI() bool {
+	for _, name := range colorCIVars {
+		if v := os.Getenv(name); v != "" && v != "false" {
+			return true
+		}
+	}
+	return false
 }
 
 type fder interface {
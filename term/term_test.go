@@ -0,0 +1,110 @@
+package term_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/term"
+)
+
+// colorEnv lists the environment variables that affect color decisions.
+var colorEnv = []string{
+	"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR", "TERM", "COLORTERM",
+	"GITHUB_ACTIONS", "GITEA_ACTIONS", "GITLAB_CI", "BUILDKITE", "CIRCLECI", "DRONE", "TRAVIS",
+}
+
+// setColorEnv clears the variables in colorEnv, then sets env, for the
+// duration of the test.
+func setColorEnv(t *testing.T, env map[string]string) {
+	for _, name := range colorEnv {
+		t.Setenv(name, env[name])
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		mode term.ColorMode
+		want bool
+	}{
+		{nil, term.ColorAuto, false},
+		{nil, term.ColorAlways, true},
+		{map[string]string{"FORCE_COLOR": "1"}, term.ColorNever, false},
+		{map[string]string{"FORCE_COLOR": "1"}, term.ColorAuto, true},
+		{map[string]string{"FORCE_COLOR": "0"}, term.ColorAuto, false},
+		{map[string]string{"FORCE_COLOR": "false", "CLICOLOR_FORCE": "1"}, term.ColorAuto, false},
+		{map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, term.ColorAuto, false},
+		{map[string]string{"NO_COLOR": "1"}, term.ColorAlways, true},
+		{map[string]string{"CLICOLOR_FORCE": "1"}, term.ColorAuto, true},
+		{map[string]string{"CLICOLOR_FORCE": "0"}, term.ColorAuto, false},
+		{map[string]string{"GITHUB_ACTIONS": "true"}, term.ColorAuto, true},
+		{map[string]string{"GITLAB_CI": "true"}, term.ColorAuto, true},
+		{map[string]string{"TRAVIS": "false"}, term.ColorAuto, false},
+		{map[string]string{"GITHUB_ACTIONS": "true", "TERM": "dumb"}, term.ColorAuto, false},
+		{map[string]string{"GITHUB_ACTIONS": "true", "CLICOLOR": "0"}, term.ColorAuto, false},
+	}
+	for _, test := range tests {
+		setColorEnv(t, test.env)
+		if want, have := test.want, term.UseColor(&bytes.Buffer{}, test.mode); want != have {
+			t.Errorf("%v mode %d: want %v, have %v", test.env, test.mode, want, have)
+		}
+	}
+}
+
+func TestDetectColorProfileForceColor(t *testing.T) {
+	for force, want := range map[string]term.ColorProfile{
+		"1": term.ANSI,
+		"2": term.ANSI256,
+		"3": term.TrueColor,
+	} {
+		setColorEnv(t, map[string]string{"FORCE_COLOR": force})
+		if have := term.DetectColorProfile(); want != have {
+			t.Errorf("FORCE_COLOR=%s: want %d, have %d", force, want, have)
+		}
+	}
+}
+
+func TestNewLoggerOptions(t *testing.T) {
+	red := func(keyvals ...interface{}) term.FgBgColor {
+		return term.FgBgColor{Fg: term.RGB(250, 10, 10)}
+	}
+	tests := []struct {
+		env     map[string]string
+		options []term.Option
+		want    string
+	}{
+		{
+			want: "a=1\n",
+		},
+		{
+			options: []term.Option{term.Colors(term.ColorAlways), term.Profile(term.ANSI)},
+			want:    "\x1b[31;1ma=1\n\x1b[39;49;22m",
+		},
+		{
+			env:     map[string]string{"FORCE_COLOR": "1"},
+			options: []term.Option{term.Colors(term.ColorNever)},
+			want:    "a=1\n",
+		},
+		{
+			env:  map[string]string{"FORCE_COLOR": "3"},
+			want: "\x1b[38;2;250;10;10ma=1\n\x1b[39;49;22m",
+		},
+		{
+			env:     map[string]string{"GITHUB_ACTIONS": "true", "TERM": "xterm-256color"},
+			options: []term.Option{term.Profile(term.TrueColor)},
+			want:    "\x1b[38;2;250;10;10ma=1\n\x1b[39;49;22m",
+		},
+	}
+	for _, test := range tests {
+		setColorEnv(t, test.env)
+		var buf bytes.Buffer
+		logger := term.NewLogger(&buf, log.NewLogfmtLogger, red, test.options...)
+		if err := logger.Log("a", 1); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := test.want, buf.String(); want != have {
+			t.Errorf("%v:\nwant %q\nhave %q", test.env, want, have)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package term
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log/internal/fields"
+	"github.com/go-kit/log/level"
+)
+
+// Theme maps level names, in lower case, to colors. Its Color method can be
+// passed to NewLogger or NewColorLogger to color log events by level:
+//
+//	logger := term.NewLogger(os.Stdout, log.NewLogfmtLogger, term.DefaultTheme().Color)
+//
+// Any level name may be added, so levels other than those of package level
+// can be colored too. Common aliases, such as warning, err and crit, take
+// the color of the level they stand for, here warn, error and critical,
+// unless they are in the Theme themselves.
+type Theme map[string]FgBgColor
+
+// DefaultTheme returns a new Theme with colors for the levels trace, debug,
+// info, notice, warn, error, critical, alert, fatal and emergency.
+func DefaultTheme() Theme {
+	return Theme{
+		"trace":     {Fg: DarkGray},
+		"debug":     {Fg: DarkGray},
+		"info":      {Fg: Gray},
+		"notice":    {Fg: Cyan},
+		"warn":      {Fg: Yellow},
+		"error":     {Fg: Red},
+		"critical":  {Fg: White, Bg: DarkRed},
+		"alert":     {Fg: White, Bg: DarkRed},
+		"fatal":     {Fg: White, Bg: DarkRed},
+		"emergency": {Fg: White, Bg: DarkRed},
+	}
+}
+
+// Color returns the color of the level of a log event: the value of the first
+// level.Key() pair in keyvals, which may be a level.Value, a string or any
+// other value formatted by fmt.Sprint. It returns the zero FgBgColor if the
+// event has no level or the level is not in t.
+func (t Theme) Color(keyvals ...interface{}) FgBgColor {
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if keyvals[i] != level.Key() {
+			continue
+		}
+		return t.Level(keyvals[i+1])
+	}
+	return FgBgColor{}
+}
+
+// Level returns the color of the level value v, which is formatted with
+// fmt.Sprint unless it is a string.
+func (t Theme) Level(v interface{}) FgBgColor {
+	name, ok := v.(string)
+	if !ok {
+		name = fmt.Sprint(v)
+	}
+	name = strings.ToLower(name)
+	if c, ok := t[name]; ok {
+		return c
+	}
+	if l, ok := fields.ParseLevel(name); ok {
+		return t[l.String()]
+	}
+	return FgBgColor{}
+}
+
+// ParseTheme parses a list of level colors separated by semicolons, such as
+//
+//	debug=darkgray;error=white:darkred+bold;notice=#00afff
+//
+// Each entry is a level name, an equals sign, the foreground color and,
+// optionally, a colon and the background color, followed by any number of
+// attributes each introduced by a plus sign. Colors are named like the Color
+// constants, in any case, or are palette numbers from 0 to 255, or are RGB
+// colors written as #rrggbb. An empty color is Default. The attributes are
+// bold, italic and underline.
+func ParseTheme(s string) (Theme, error) {
+	t := Theme{}
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		eq := strings.IndexByte(entry, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid theme entry %q", entry)
+		}
+		c, err := parseFgBgColor(entry[eq+1:])
+		if err != nil {
+			return nil, fmt.Errorf("theme entry %q: %w", entry, err)
+		}
+		t[strings.ToLower(strings.TrimSpace(entry[:eq]))] = c
+	}
+	return t, nil
+}
+
+// LoadTheme returns DefaultTheme updated with the colors in the environment
+// variable name, in the format understood by ParseTheme. If the variable is
+// malformed it returns DefaultTheme and the error.
+func LoadTheme(name string) (Theme, error) {
+	t := DefaultTheme()
+	overrides, err := ParseTheme(os.Getenv(name))
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", name, err)
+	}
+	for lvl, c := range overrides {
+		t[lvl] = c
+	}
+	return t, nil
+}
+
+// parseFgBgColor parses the color of a theme entry.
+func parseFgBgColor(s string) (FgBgColor, error) {
+	var c FgBgColor
+	parts := strings.Split(s, "+")
+	for _, a := range parts[1:] {
+		switch strings.ToLower(strings.TrimSpace(a)) {
+		case "bold":
+			c.Attr |= Bold
+		case "italic":
+			c.Attr |= Italic
+		case "underline":
+			c.Attr |= Underline
+		default:
+			return FgBgColor{}, fmt.Errorf("unknown attribute %q", a)
+		}
+	}
+	fg, bg := parts[0], ""
+	if i := strings.IndexByte(fg, ':'); i >= 0 {
+		fg, bg = fg[:i], fg[i+1:]
+	}
+	var err error
+	if c.Fg, err = parseColor(fg); err != nil {
+		return FgBgColor{}, err
+	}
+	if c.Bg, err = parseColor(bg); err != nil {
+		return FgBgColor{}, err
+	}
+	return c, nil
+}
+
+var colorNames = map[string]Color{
+	"default":     Default,
+	"black":       Black,
+	"darkred":     DarkRed,
+	"darkgreen":   DarkGreen,
+	"brown":       Brown,
+	"darkblue":    DarkBlue,
+	"darkmagenta": DarkMagenta,
+	"darkcyan":    DarkCyan,
+	"gray":        Gray,
+	"darkgray":    DarkGray,
+	"red":         Red,
+	"green":       Green,
+	"yellow":      Yellow,
+	"blue":        Blue,
+	"magenta":     Magenta,
+	"cyan":        Cyan,
+	"white":       White,
+}
+
+// parseColor parses a color name, palette number or #rrggbb color.
+func parseColor(s string) (Color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Default, nil
+	}
+	if c, ok := colorNames[s]; ok {
+		return c, nil
+	}
+	if strings.HasPrefix(s, "#") && len(s) == 7 {
+		if rgb, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			return RGB(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)), nil
+		}
+	}
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return Palette(uint8(n)), nil
+	}
+	return Default, fmt.Errorf("unknown color %q", s)
+}
@@ -0,0 +1,159 @@
+package term_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-kit/log/term"
+)
+
+func TestThemeColor(t *testing.T) {
+	t.Parallel()
+	theme := term.DefaultTheme()
+	theme["audit"] = term.FgBgColor{Fg: term.Magenta}
+	tests := []struct {
+		keyvals []interface{}
+		want    term.FgBgColor
+	}{
+		{[]interface{}{"level", level.WarnValue(), "msg", "m"}, term.FgBgColor{Fg: term.Yellow}},
+		{[]interface{}{"msg", "m", "level", level.DebugValue()}, term.FgBgColor{Fg: term.DarkGray}},
+		{[]interface{}{"level", "ERROR"}, term.FgBgColor{Fg: term.Red}},
+		{[]interface{}{"level", "audit"}, term.FgBgColor{Fg: term.Magenta}},
+		{[]interface{}{"level", "Warning"}, term.FgBgColor{Fg: term.Yellow}},
+		{[]interface{}{"level", "err"}, term.FgBgColor{Fg: term.Red}},
+		{[]interface{}{"level", "critical"}, term.FgBgColor{Fg: term.White, Bg: term.DarkRed}},
+		{[]interface{}{"level", "emergency"}, term.FgBgColor{Fg: term.White, Bg: term.DarkRed}},
+		{[]interface{}{"level", "crit"}, term.FgBgColor{Fg: term.White, Bg: term.DarkRed}},
+		{[]interface{}{"level", "unknown"}, term.FgBgColor{}},
+		{[]interface{}{"msg", "no level"}, term.FgBgColor{}},
+		{[]interface{}{"level"}, term.FgBgColor{}},
+	}
+	for _, test := range tests {
+		if want, have := test.want, theme.Color(test.keyvals...); want != have {
+			t.Errorf("%v: want %+v, have %+v", test.keyvals, want, have)
+		}
+	}
+}
+
+// levelName is a fmt.Stringer whose String method panics on a nil pointer.
+type levelName struct {
+	name string
+}
+
+func (l *levelName) String() string { return l.name }
+
+func TestThemeLevelStringer(t *testing.T) {
+	t.Parallel()
+	theme := term.DefaultTheme()
+	if want, have := (term.FgBgColor{Fg: term.Cyan}), theme.Level(&levelName{"notice"}); want != have {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+	var nilLevel *levelName
+	if want, have := (term.FgBgColor{}), theme.Level(nilLevel); want != have {
+		t.Errorf("nil: want %+v, have %+v", want, have)
+	}
+}
+
+func TestThemeAliases(t *testing.T) {
+	t.Parallel()
+	theme := term.Theme{"warn": {Fg: term.Magenta}, "err": {Fg: term.Blue}}
+	for _, test := range []struct {
+		level string
+		want  term.FgBgColor
+	}{
+		{"warning", term.FgBgColor{Fg: term.Magenta}},
+		{"err", term.FgBgColor{Fg: term.Blue}},
+		{"error", term.FgBgColor{}},
+	} {
+		if want, have := test.want, theme.Level(test.level); want != have {
+			t.Errorf("%s: want %+v, have %+v", test.level, want, have)
+		}
+	}
+}
+
+func TestParseTheme(t *testing.T) {
+	t.Parallel()
+	theme, err := term.ParseTheme(" debug=darkgray; Error=White:DarkRed+bold ;notice=#00afff;trace=:17+italic+underline;info=;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := term.Theme{
+		"debug":  {Fg: term.DarkGray},
+		"error":  {Fg: term.White, Bg: term.DarkRed, Attr: term.Bold},
+		"notice": {Fg: term.RGB(0, 0xaf, 0xff)},
+		"trace":  {Bg: term.Palette(17), Attr: term.Italic | term.Underline},
+		"info":   {},
+	}
+	if !reflect.DeepEqual(want, theme) {
+		t.Errorf("\nwant %+v\nhave %+v", want, theme)
+	}
+
+	for _, s := range []string{
+		"debug",
+		"=red",
+		"debug=purple",
+		"debug=256",
+		"debug=#12345",
+		"debug=red:#xyzxyz",
+		"debug=red+blink",
+	} {
+		if _, err := term.ParseTheme(s); err == nil {
+			t.Errorf("%q: want error, have nil", s)
+		}
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	t.Setenv("TEST_LOG_COLORS", "info=green;audit=magenta")
+	theme, err := term.LoadTheme("TEST_LOG_COLORS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := (term.FgBgColor{Fg: term.Green}), theme["info"]; want != have {
+		t.Errorf("info: want %+v, have %+v", want, have)
+	}
+	if want, have := (term.FgBgColor{Fg: term.Magenta}), theme["audit"]; want != have {
+		t.Errorf("audit: want %+v, have %+v", want, have)
+	}
+	if want, have := term.DefaultTheme()["warn"], theme["warn"]; want != have {
+		t.Errorf("warn: want %+v, have %+v", want, have)
+	}
+
+	t.Setenv("TEST_LOG_COLORS", "info=nope")
+	theme, err = term.LoadTheme("TEST_LOG_COLORS")
+	if err == nil {
+		t.Error("want error, have nil")
+	}
+	if want, have := term.DefaultTheme(), theme; !reflect.DeepEqual(want, have) {
+		t.Errorf("\nwant %+v\nhave %+v", want, have)
+	}
+}
+
+func TestThemeColorLogger(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	logger := term.NewColorLogger(&buf, log.NewLogfmtLogger, term.DefaultTheme().Color)
+	if err := level.Error(logger).Log("msg", "failed"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "\x1b[31;1mlevel=error msg=failed\n\x1b[39;49;22m", buf.String(); want != have {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+}
+
+func TestConsoleLoggerTheme(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	theme := term.Theme{"audit": {Fg: term.Magenta}}
+	logger := term.NewConsoleLogger(&buf, term.ConsoleColors(term.ColorAlways), term.ConsoleTheme(theme))
+	if err := logger.Log("ts", consoleTime, "level", "audit", "msg", "login"); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[30;1m14:03:27.512\x1b[39;49;22m \x1b[35;1mAUD\x1b[39;49;22m  login\n"
+	if have := buf.String(); want != have {
+		t.Errorf("\nwant %q\nhave %q", want, have)
+	}
+}
@@ -69,6 +69,13 @@ func (tf timeFormat) String() string {
 	return tf.time.Format(tf.layout)
 }
 
+// Time returns the instant tf formats. Encoders outside this package that
+// have a native time representation can recover it through an interface such
+// as interface{ Time() time.Time }.
+func (tf timeFormat) Time() time.Time {
+	return tf.time
+}
+
 // MarshalText implements encoding.TextMarshaller.
 func (tf timeFormat) MarshalText() (text []byte, err error) {
 	// The following code adapted from the standard library time.Time.Format